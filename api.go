@@ -0,0 +1,506 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/josequiceno2000/gator/internal/database"
+)
+
+// The REST API served by `gator serve`. Every endpoint except the OpenAPI
+// document needs an API token from `gator token create`, sent as
+// "Authorization: Bearer <token>", and acts as the token's user.
+
+//go:embed api/openapi.json
+var openAPIDocument []byte
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 200
+)
+
+func handlerServe(s *state, cmd command) error {
+	listen := cmd.stringFlag("listen")
+
+	ctx, stop := signal.NotifyContext(cmd.Context, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	ln, err := net.Listen("tcp", listen)
+	if err != nil {
+		return fmt.Errorf("serve: failed to listen: %w", err)
+	}
+
+	srv := &http.Server{Handler: newAPIHandler(s), ReadHeaderTimeout: 5 * time.Second}
+	errc := make(chan error, 1)
+	go func() {
+		errc <- srv.Serve(ln)
+	}()
+	slog.Info("serving API", "addr", ln.Addr().String())
+
+	select {
+	case err := <-errc:
+		return fmt.Errorf("serve: %w", err)
+	case <-ctx.Done():
+	}
+
+	slog.Info("shutting down")
+	stopServer(srv)
+	return nil
+}
+
+type apiServer struct {
+	s *state
+}
+
+type apiHandlerFunc func(w http.ResponseWriter, r *http.Request, user database.User)
+
+func newAPIHandler(s *state) http.Handler {
+	api := &apiServer{s: s}
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /api/v1/openapi.json", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(openAPIDocument)
+	})
+
+	mux.Handle("GET /api/v1/me", api.auth(api.getMe))
+	mux.Handle("GET /api/v1/users", api.auth(api.listUsers))
+	mux.Handle("GET /api/v1/feeds", api.auth(api.listFeeds))
+	mux.Handle("POST /api/v1/feeds", api.auth(api.createFeed))
+	mux.Handle("GET /api/v1/follows", api.auth(api.listFollows))
+	mux.Handle("POST /api/v1/follows", api.auth(api.createFollow))
+	mux.Handle("DELETE /api/v1/follows", api.auth(api.deleteFollow))
+	mux.Handle("GET /api/v1/posts", api.auth(api.listPosts))
+	mux.Handle("GET /api/v1/posts/{id}", api.auth(api.getPost))
+	mux.Handle("PUT /api/v1/posts/{id}/read", api.auth(api.markPost(true, true)))
+	mux.Handle("DELETE /api/v1/posts/{id}/read", api.auth(api.markPost(true, false)))
+	mux.Handle("PUT /api/v1/posts/{id}/saved", api.auth(api.markPost(false, true)))
+	mux.Handle("DELETE /api/v1/posts/{id}/saved", api.auth(api.markPost(false, false)))
+
+	registerReaderAPI(mux, s)
+
+	return mux
+}
+
+// auth resolves the bearer token to a user before calling next.
+func (api *apiServer) auth(next apiHandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || token == "" {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="gator"`)
+			writeAPIError(w, http.StatusUnauthorized, "missing bearer token")
+			return
+		}
+
+		user, err := userForAPIToken(r.Context(), api.s, token)
+		if errors.Is(err, sql.ErrNoRows) {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="gator", error="invalid_token"`)
+			writeAPIError(w, http.StatusUnauthorized, "invalid token")
+			return
+		}
+		if err != nil {
+			api.internalError(w, r, err)
+			return
+		}
+
+		next(w, r, user)
+	})
+}
+
+func writeAPIError(w http.ResponseWriter, code int, msg string) {
+	writeJSON(w, code, map[string]string{"error": msg})
+}
+
+// internalError logs err and answers without details, which could leak
+// database internals to clients.
+func (api *apiServer) internalError(w http.ResponseWriter, r *http.Request, err error) {
+	slog.Error("API request failed", "method", r.Method, "path", r.URL.Path, "err", err)
+	writeAPIError(w, http.StatusInternalServerError, "internal error")
+}
+
+// decodeBody parses a JSON request body into v, answering with 400 if it
+// can't.
+func decodeBody(w http.ResponseWriter, r *http.Request, v any) bool {
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		writeAPIError(w, http.StatusBadRequest, "invalid request body: "+err.Error())
+		return false
+	}
+	return true
+}
+
+type apiUser struct {
+	ID        uuid.UUID `json:"id"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type apiFeed struct {
+	ID        uuid.UUID `json:"id"`
+	Name      string    `json:"name"`
+	URL       string    `json:"url"`
+	AddedBy   string    `json:"added_by,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type apiFollow struct {
+	FeedID      uuid.UUID `json:"feed_id"`
+	FeedURL     string    `json:"feed_url"`
+	FeedName    string    `json:"feed_name"`
+	DisplayName *string   `json:"display_name"`
+	Folder      *string   `json:"folder"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type apiPost struct {
+	ID          uuid.UUID  `json:"id"`
+	FeedID      uuid.UUID  `json:"feed_id"`
+	FeedName    string     `json:"feed_name"`
+	Title       string     `json:"title"`
+	URL         string     `json:"url"`
+	Author      *string    `json:"author"`
+	Description *string    `json:"description"`
+	PublishedAt time.Time  `json:"published_at"`
+	ReadAt      *time.Time `json:"read_at"`
+	SavedAt     *time.Time `json:"saved_at"`
+}
+
+type apiPostPage struct {
+	Posts []apiPost `json:"posts"`
+	// NextOffset is where the next page starts, and absent on the last page
+	NextOffset *int `json:"next_offset,omitempty"`
+}
+
+func stringOrNil(s sql.NullString) *string {
+	if !s.Valid {
+		return nil
+	}
+	return &s.String
+}
+
+func nullTimeOrNil(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
+
+func toAPIPost(row database.GetPostsForUserRow) apiPost {
+	return apiPost{
+		ID:          row.ID,
+		FeedID:      row.FeedID,
+		FeedName:    row.FeedName,
+		Title:       row.Title,
+		URL:         row.Url,
+		Author:      stringOrNil(row.Author),
+		Description: stringOrNil(row.Description),
+		PublishedAt: row.PublishedAt,
+		ReadAt:      nullTimeOrNil(row.ReadAt),
+		SavedAt:     nullTimeOrNil(row.SavedAt),
+	}
+}
+
+func (api *apiServer) getMe(w http.ResponseWriter, r *http.Request, user database.User) {
+	writeJSON(w, http.StatusOK, apiUser{ID: user.ID, Name: user.Name, CreatedAt: user.CreatedAt})
+}
+
+func (api *apiServer) listUsers(w http.ResponseWriter, r *http.Request, user database.User) {
+	names, err := api.s.DB.GetUsers(r.Context())
+	if err != nil {
+		api.internalError(w, r, err)
+		return
+	}
+
+	users := make([]map[string]string, len(names))
+	for i, name := range names {
+		users[i] = map[string]string{"name": name}
+	}
+	writeJSON(w, http.StatusOK, users)
+}
+
+func (api *apiServer) listFeeds(w http.ResponseWriter, r *http.Request, user database.User) {
+	rows, err := api.s.DB.GetFeedsWithUserNames(r.Context())
+	if err != nil {
+		api.internalError(w, r, err)
+		return
+	}
+
+	feeds := make([]apiFeed, len(rows))
+	for i, row := range rows {
+		feeds[i] = apiFeed{ID: row.ID, Name: row.Name, URL: row.Url, AddedBy: row.UserName, CreatedAt: row.CreatedAt}
+	}
+	writeJSON(w, http.StatusOK, feeds)
+}
+
+func (api *apiServer) createFeed(w http.ResponseWriter, r *http.Request, user database.User) {
+	var body struct {
+		Name string `json:"name"`
+		URL  string `json:"url"`
+	}
+	if !decodeBody(w, r, &body) {
+		return
+	}
+	if body.Name == "" || body.URL == "" {
+		writeAPIError(w, http.StatusBadRequest, "name and url are required")
+		return
+	}
+
+	feed, err := addFeed(r.Context(), api.s, user.ID, body.Name, body.URL)
+	if err != nil {
+		if isUniqueViolation(err) {
+			writeAPIError(w, http.StatusConflict, "a feed with this url already exists; follow it instead")
+			return
+		}
+		api.internalError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, apiFeed{ID: feed.ID, Name: feed.Name, URL: feed.Url, AddedBy: user.Name, CreatedAt: feed.CreatedAt})
+}
+
+func (api *apiServer) listFollows(w http.ResponseWriter, r *http.Request, user database.User) {
+	rows, err := api.s.DB.GetFeedFollowsForUser(r.Context(), user.ID)
+	if err != nil {
+		api.internalError(w, r, err)
+		return
+	}
+
+	follows := make([]apiFollow, len(rows))
+	for i, row := range rows {
+		follows[i] = apiFollow{
+			FeedID:      row.FeedID,
+			FeedURL:     row.FeedUrl,
+			FeedName:    row.FeedName,
+			DisplayName: stringOrNil(row.DisplayName),
+			Folder:      stringOrNil(row.Folder),
+			CreatedAt:   row.CreatedAt,
+		}
+	}
+	writeJSON(w, http.StatusOK, follows)
+}
+
+func (api *apiServer) createFollow(w http.ResponseWriter, r *http.Request, user database.User) {
+	var body struct {
+		URL string `json:"url"`
+	}
+	if !decodeBody(w, r, &body) {
+		return
+	}
+
+	feed, err := api.s.DB.GetFeedByUrl(r.Context(), body.URL)
+	if errors.Is(err, sql.ErrNoRows) {
+		writeAPIError(w, http.StatusNotFound, "no feed with this url; add it first")
+		return
+	}
+	if err != nil {
+		api.internalError(w, r, err)
+		return
+	}
+
+	now := time.Now().UTC()
+	row, err := api.s.DB.CreateFeedFollow(r.Context(), database.CreateFeedFollowParams{
+		ID:        uuid.New(),
+		CreatedAt: now,
+		UpdatedAt: now,
+		UserID:    user.ID,
+		FeedID:    feed.ID,
+	})
+	if err != nil {
+		if isUniqueViolation(err) {
+			writeAPIError(w, http.StatusConflict, "already following this feed")
+			return
+		}
+		api.internalError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, apiFollow{
+		FeedID:    row.FeedID,
+		FeedURL:   feed.Url,
+		FeedName:  row.FeedName,
+		CreatedAt: row.CreatedAt,
+	})
+}
+
+func (api *apiServer) deleteFollow(w http.ResponseWriter, r *http.Request, user database.User) {
+	url := r.URL.Query().Get("url")
+	if url == "" {
+		writeAPIError(w, http.StatusBadRequest, "url query parameter is required")
+		return
+	}
+
+	err := api.s.DB.DeleteFeedFollow(r.Context(), database.DeleteFeedFollowParams{UserID: user.ID, Url: url})
+	if err != nil {
+		api.internalError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (api *apiServer) listPosts(w http.ResponseWriter, r *http.Request, user database.User) {
+	query := r.URL.Query()
+	params := database.GetPostsForUserParams{UserID: user.ID}
+
+	limit, err := intParam(query.Get("limit"), defaultPageSize, 1, maxPageSize)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, "limit: "+err.Error())
+		return
+	}
+	offset, err := intParam(query.Get("offset"), 0, 0, math.MaxInt32)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, "offset: "+err.Error())
+		return
+	}
+	// One extra post tells whether there is another page
+	params.Limit = int32(limit + 1)
+	params.Offset = int32(offset)
+
+	if folder := query.Get("folder"); folder != "" {
+		params.Folder = sql.NullString{String: folder, Valid: true}
+	}
+	if feedID := query.Get("feed_id"); feedID != "" {
+		id, err := uuid.Parse(feedID)
+		if err != nil {
+			writeAPIError(w, http.StatusBadRequest, "feed_id: not a valid id")
+			return
+		}
+		params.FeedID = uuid.NullUUID{UUID: id, Valid: true}
+	}
+	for _, filter := range []struct {
+		name  string
+		field *bool
+	}{{"unread", &params.UnreadOnly}, {"saved", &params.SavedOnly}} {
+		if value := query.Get(filter.name); value != "" {
+			b, err := strconv.ParseBool(value)
+			if err != nil {
+				writeAPIError(w, http.StatusBadRequest, filter.name+": must be true or false")
+				return
+			}
+			*filter.field = b
+		}
+	}
+
+	rows, err := api.s.DB.GetPostsForUser(r.Context(), params)
+	if err != nil {
+		api.internalError(w, r, err)
+		return
+	}
+
+	page := apiPostPage{Posts: []apiPost{}}
+	if len(rows) > limit {
+		rows = rows[:limit]
+		next := offset + limit
+		page.NextOffset = &next
+	}
+	for _, row := range rows {
+		page.Posts = append(page.Posts, toAPIPost(row))
+	}
+	writeJSON(w, http.StatusOK, page)
+}
+
+// intParam parses an integer query parameter from lo to hi, using def
+// when it's empty.
+func intParam(value string, def, lo, hi int) (int, error) {
+	if value == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < lo || n > hi {
+		return 0, fmt.Errorf("must be a number from %d to %d", lo, hi)
+	}
+	return n, nil
+}
+
+// lookupPost returns the post named by the request path if user follows
+// its feed, answering with an error otherwise.
+func (api *apiServer) lookupPost(w http.ResponseWriter, r *http.Request, user database.User) (database.GetPostForUserRow, bool) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		writeAPIError(w, http.StatusNotFound, "post not found")
+		return database.GetPostForUserRow{}, false
+	}
+
+	post, err := api.s.DB.GetPostForUser(r.Context(), database.GetPostForUserParams{UserID: user.ID, ID: id})
+	if errors.Is(err, sql.ErrNoRows) {
+		writeAPIError(w, http.StatusNotFound, "post not found")
+		return post, false
+	}
+	if err != nil {
+		api.internalError(w, r, err)
+		return post, false
+	}
+	return post, true
+}
+
+func (api *apiServer) getPost(w http.ResponseWriter, r *http.Request, user database.User) {
+	post, ok := api.lookupPost(w, r, user)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, toAPIPost(database.GetPostsForUserRow(post)))
+}
+
+// markPost returns a handler that marks a post read (or saved, when read
+// is false) if set is true, and clears the mark otherwise.
+func (api *apiServer) markPost(read, set bool) apiHandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, user database.User) {
+		post, ok := api.lookupPost(w, r, user)
+		if !ok {
+			return
+		}
+
+		if err := setPostState(r.Context(), api.s.DB, user.ID, post.ID, read, set); err != nil {
+			api.internalError(w, r, err)
+			return
+		}
+
+		post, err := api.s.DB.GetPostForUser(r.Context(), database.GetPostForUserParams{UserID: user.ID, ID: post.ID})
+		if err != nil {
+			api.internalError(w, r, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, toAPIPost(database.GetPostsForUserRow(post)))
+	}
+}
+
+// setPostState marks postID read (or saved, when read is false) for userID
+// if set is true, and clears the mark otherwise.
+func setPostState(ctx context.Context, q database.Querier, userID, postID uuid.UUID, read, set bool) error {
+	now := time.Now().UTC()
+	at := sql.NullTime{Time: now, Valid: set}
+
+	if read {
+		return q.SetPostRead(ctx, database.SetPostReadParams{
+			ID:        uuid.New(),
+			CreatedAt: now,
+			UpdatedAt: now,
+			UserID:    userID,
+			PostID:    postID,
+			ReadAt:    at,
+		})
+	}
+	return q.SetPostSaved(ctx, database.SetPostSavedParams{
+		ID:        uuid.New(),
+		CreatedAt: now,
+		UpdatedAt: now,
+		UserID:    userID,
+		PostID:    postID,
+		SavedAt:   at,
+	})
+}
@@ -0,0 +1,334 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// apiFixture is a `gator serve` API over the in-memory store, with alice
+// following a feed of five posts.
+type apiFixture struct {
+	s       *state
+	srv     *httptest.Server
+	feedURL string
+	alice   string // alice's API token
+}
+
+func newAPIFixture(t *testing.T) *apiFixture {
+	t.Helper()
+
+	s := newTestState(t)
+	f := &apiFixture{
+		s:       s,
+		feedURL: newFeedServer(t, "Post 1", "Post 2", "Post 3", "Post 4", "Post 5").URL + "/feed.xml",
+	}
+
+	mustRun(t, s, "register", "alice")
+	mustRun(t, s, "addfeed", "Blog", f.feedURL)
+	aggregate(t, s)
+	f.alice = createToken(t, s, "alice")
+
+	f.srv = httptest.NewServer(newAPIHandler(s))
+	t.Cleanup(f.srv.Close)
+	return f
+}
+
+// createToken logs in as user and returns a new API token for them.
+func createToken(t *testing.T, s *state, user string) string {
+	t.Helper()
+	mustRun(t, s, "login", user)
+	out := mustRun(t, s, "token", "create", "test")
+	token, _, _ := strings.Cut(out, "\n")
+	if !strings.HasPrefix(token, apiTokenPrefix) {
+		t.Fatalf("token create printed %q", out)
+	}
+	return token
+}
+
+// request sends an API request with token as the bearer token, if it isn't
+// empty, and returns the response status and body.
+func (f *apiFixture) request(t *testing.T, method, path, token, body string) (int, []byte) {
+	t.Helper()
+
+	req, err := http.NewRequest(method, f.srv.URL+path, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp.StatusCode, data
+}
+
+// get sends a GET request expected to succeed and decodes its JSON body
+// into v.
+func (f *apiFixture) get(t *testing.T, path, token string, v any) {
+	t.Helper()
+	code, body := f.request(t, http.MethodGet, path, token, "")
+	if code != http.StatusOK {
+		t.Fatalf("GET %s: status %d: %s", path, code, body)
+	}
+	if err := json.Unmarshal(body, v); err != nil {
+		t.Fatalf("GET %s: %v: %s", path, err, body)
+	}
+}
+
+func (f *apiFixture) posts(t *testing.T, query, token string) apiPostPage {
+	t.Helper()
+	var page apiPostPage
+	f.get(t, "/api/v1/posts"+query, token, &page)
+	return page
+}
+
+func postTitles(page apiPostPage) []string {
+	titles := make([]string, len(page.Posts))
+	for i, post := range page.Posts {
+		titles[i] = post.Title
+	}
+	return titles
+}
+
+func TestAPIAuthentication(t *testing.T) {
+	f := newAPIFixture(t)
+
+	tests := []struct {
+		name  string
+		token string
+		want  int
+	}{
+		{"missing token", "", http.StatusUnauthorized},
+		{"invalid token", apiTokenPrefix + "0000", http.StatusUnauthorized},
+		{"valid token", f.alice, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, body := f.request(t, http.MethodGet, "/api/v1/me", tt.token, "")
+			if code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", code, tt.want, body)
+			}
+		})
+	}
+
+	// A non-bearer scheme is refused with a challenge
+	req, _ := http.NewRequest(http.MethodGet, f.srv.URL+"/api/v1/posts", nil)
+	req.Header.Set("Authorization", "Basic "+f.alice)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized || !strings.HasPrefix(resp.Header.Get("WWW-Authenticate"), "Bearer") {
+		t.Errorf("Basic auth: status %d, WWW-Authenticate %q", resp.StatusCode, resp.Header.Get("WWW-Authenticate"))
+	}
+
+	var me apiUser
+	f.get(t, "/api/v1/me", f.alice, &me)
+	if me.Name != "alice" {
+		t.Errorf("me = %q, want alice", me.Name)
+	}
+
+	// The OpenAPI document is public
+	var doc map[string]any
+	f.get(t, "/api/v1/openapi.json", "", &doc)
+	if doc["openapi"] == nil {
+		t.Errorf("openapi.json has no openapi version")
+	}
+
+	// Revoked tokens stop working
+	mustRun(t, f.s, "login", "alice")
+	mustRun(t, f.s, "token", "rm", "test")
+	if code, _ := f.request(t, http.MethodGet, "/api/v1/me", f.alice, ""); code != http.StatusUnauthorized {
+		t.Errorf("revoked token: status = %d, want 401", code)
+	}
+}
+
+func TestAPIPostsPagination(t *testing.T) {
+	f := newAPIFixture(t)
+
+	var titles []string
+	offset, pages := "0", 0
+	for {
+		page := f.posts(t, "?limit=2&offset="+offset, f.alice)
+		titles = append(titles, postTitles(page)...)
+		pages++
+		if page.NextOffset == nil {
+			break
+		}
+		offset = strconv.Itoa(*page.NextOffset)
+		if pages > 5 {
+			t.Fatal("pagination doesn't end")
+		}
+	}
+
+	want := []string{"Post 1", "Post 2", "Post 3", "Post 4", "Post 5"}
+	if strings.Join(titles, ",") != strings.Join(want, ",") || pages != 3 {
+		t.Errorf("got %v over %d pages, want %v over 3", titles, pages, want)
+	}
+
+	// A page ending exactly at the last post has no next page
+	if page := f.posts(t, "?limit=5", f.alice); page.NextOffset != nil || len(page.Posts) != 5 {
+		t.Errorf("limit=5: %d posts, next_offset %v", len(page.Posts), page.NextOffset)
+	}
+
+	for _, query := range []string{"?limit=0", "?limit=201", "?offset=-1", "?offset=3000000000", "?limit=x", "?unread=maybe", "?feed_id=nope"} {
+		if code, body := f.request(t, http.MethodGet, "/api/v1/posts"+query, f.alice, ""); code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400: %s", query, code, body)
+		}
+	}
+}
+
+func TestAPIReadAndSavedState(t *testing.T) {
+	f := newAPIFixture(t)
+	post := f.posts(t, "?limit=1", f.alice).Posts[0]
+	path := "/api/v1/posts/" + post.ID.String()
+
+	mark := func(method, state string) apiPost {
+		t.Helper()
+		code, body := f.request(t, method, path+"/"+state, f.alice, "")
+		if code != http.StatusOK {
+			t.Fatalf("%s %s/%s: status %d: %s", method, path, state, code, body)
+		}
+		var updated apiPost
+		if err := json.Unmarshal(body, &updated); err != nil {
+			t.Fatal(err)
+		}
+		return updated
+	}
+
+	if updated := mark(http.MethodPut, "read"); updated.ReadAt == nil {
+		t.Error("PUT read didn't set read_at")
+	}
+	if page := f.posts(t, "?unread=true", f.alice); len(page.Posts) != 4 || strings.Contains(strings.Join(postTitles(page), ","), post.Title) {
+		t.Errorf("unread posts after reading %q: %v", post.Title, postTitles(page))
+	}
+
+	if updated := mark(http.MethodDelete, "read"); updated.ReadAt != nil {
+		t.Error("DELETE read didn't clear read_at")
+	}
+	if page := f.posts(t, "?unread=true", f.alice); len(page.Posts) != 5 {
+		t.Errorf("unread posts after unreading: %v", postTitles(page))
+	}
+
+	if page := f.posts(t, "?saved=true", f.alice); len(page.Posts) != 0 {
+		t.Errorf("saved posts before saving: %v", postTitles(page))
+	}
+	if updated := mark(http.MethodPut, "saved"); updated.SavedAt == nil {
+		t.Error("PUT saved didn't set saved_at")
+	}
+	if page := f.posts(t, "?saved=true", f.alice); len(page.Posts) != 1 || page.Posts[0].ID != post.ID {
+		t.Errorf("saved posts: %v", postTitles(page))
+	}
+	if updated := mark(http.MethodDelete, "saved"); updated.SavedAt != nil {
+		t.Error("DELETE saved didn't clear saved_at")
+	}
+
+	var got apiPost
+	f.get(t, path, f.alice, &got)
+	if got.ReadAt != nil || got.SavedAt != nil {
+		t.Errorf("post after clearing both marks: read_at %v, saved_at %v", got.ReadAt, got.SavedAt)
+	}
+
+	if code, _ := f.request(t, http.MethodPut, "/api/v1/posts/00000000-0000-0000-0000-000000000000/read", f.alice, ""); code != http.StatusNotFound {
+		t.Errorf("marking a missing post: status = %d, want 404", code)
+	}
+}
+
+func TestAPIUsersAreIsolated(t *testing.T) {
+	f := newAPIFixture(t)
+	mustRun(t, f.s, "register", "bob")
+	bob := createToken(t, f.s, "bob")
+
+	alicePost := f.posts(t, "?limit=1", f.alice).Posts[0]
+	path := "/api/v1/posts/" + alicePost.ID.String()
+
+	// bob doesn't follow the feed, so its posts don't exist for him
+	if page := f.posts(t, "", bob); len(page.Posts) != 0 {
+		t.Errorf("bob sees posts without following: %v", postTitles(page))
+	}
+	for _, method := range []string{http.MethodGet, http.MethodPut} {
+		target := path
+		if method == http.MethodPut {
+			target += "/read"
+		}
+		if code, _ := f.request(t, method, target, bob, ""); code != http.StatusNotFound {
+			t.Errorf("%s %s as bob: status = %d, want 404", method, target, code)
+		}
+	}
+
+	// Once both follow it, read and saved marks stay per user
+	code, body := f.request(t, http.MethodPost, "/api/v1/follows", bob, `{"url": "`+f.feedURL+`"}`)
+	if code != http.StatusCreated {
+		t.Fatalf("bob following: status %d: %s", code, body)
+	}
+	if code, _ := f.request(t, http.MethodPut, path+"/read", bob, ""); code != http.StatusOK {
+		t.Fatalf("bob marking read: status %d", code)
+	}
+	if code, _ := f.request(t, http.MethodPut, path+"/saved", bob, ""); code != http.StatusOK {
+		t.Fatalf("bob saving: status %d", code)
+	}
+
+	var got apiPost
+	f.get(t, path, f.alice, &got)
+	if got.ReadAt != nil || got.SavedAt != nil {
+		t.Errorf("bob's marks leaked to alice: read_at %v, saved_at %v", got.ReadAt, got.SavedAt)
+	}
+	if page := f.posts(t, "?unread=true", f.alice); len(page.Posts) != 5 {
+		t.Errorf("alice's unread posts: %v", postTitles(page))
+	}
+	if page := f.posts(t, "?unread=true", bob); len(page.Posts) != 4 {
+		t.Errorf("bob's unread posts: %v", postTitles(page))
+	}
+
+	// Unfollowing only affects the caller
+	if code, _ := f.request(t, http.MethodDelete, "/api/v1/follows?url="+f.feedURL, bob, ""); code != http.StatusNoContent {
+		t.Errorf("bob unfollowing: status %d", code)
+	}
+	var follows []apiFollow
+	f.get(t, "/api/v1/follows", f.alice, &follows)
+	if len(follows) != 1 {
+		t.Errorf("alice's follows after bob unfollowed: %v", follows)
+	}
+}
+
+func TestAPIFeedsAndFollows(t *testing.T) {
+	f := newAPIFixture(t)
+	otherURL := newFeedServer(t, "Other").URL + "/feed.xml"
+
+	code, body := f.request(t, http.MethodPost, "/api/v1/feeds", f.alice, `{"name": "Other", "url": "`+otherURL+`"}`)
+	if code != http.StatusCreated {
+		t.Fatalf("creating a feed: status %d: %s", code, body)
+	}
+	if code, _ := f.request(t, http.MethodPost, "/api/v1/feeds", f.alice, `{"name": "Again", "url": "`+otherURL+`"}`); code != http.StatusConflict {
+		t.Errorf("creating an existing feed: status = %d, want 409", code)
+	}
+	if code, _ := f.request(t, http.MethodPost, "/api/v1/feeds", f.alice, `{"name": "x", "url": "y", "extra": 1}`); code != http.StatusBadRequest {
+		t.Errorf("unknown field: status = %d, want 400", code)
+	}
+	if code, _ := f.request(t, http.MethodPost, "/api/v1/follows", f.alice, `{"url": "`+otherURL+`"}`); code != http.StatusConflict {
+		t.Errorf("following a feed twice: status = %d, want 409", code)
+	}
+	if code, _ := f.request(t, http.MethodPost, "/api/v1/follows", f.alice, `{"url": "https://example.com/none"}`); code != http.StatusNotFound {
+		t.Errorf("following a missing feed: status = %d, want 404", code)
+	}
+
+	var feeds []apiFeed
+	f.get(t, "/api/v1/feeds", f.alice, &feeds)
+	if len(feeds) != 2 {
+		t.Errorf("feeds: %v", feeds)
+	}
+}
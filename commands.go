@@ -3,7 +3,14 @@ package main
 import (
 	"context"
 	"database/sql"
+	"flag"
 	"fmt"
+	"io"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/josequiceno2000/gator/internal/config"
 	"github.com/josequiceno2000/gator/internal/database"
@@ -11,20 +18,62 @@ import (
 )
 
 type state struct {
-	DB *database.Queries
+	DB database.Querier
+	Conn *sql.DB
 	CfgPointer *config.Config
+	Backend backend
+}
+
+// withTx runs fn against queries bound to a single transaction, committing
+// when fn succeeds and rolling back when it returns an error. Without a
+// connection, as with the in-memory store, fn runs directly against s.DB.
+func (s *state) withTx(ctx context.Context, fn func(q database.Querier) error) error {
+	if s.Conn == nil {
+		return fn(s.DB)
+	}
+
+	tx, err := s.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := fn(s.Backend.queries(tx)); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
 type command struct {
 	Name string
 	Arguments []string
+	// Context is cancelled when the command should stop early
+	Context context.Context
+	// Flags holds the parsed values of the command's flags, if it has any
+	Flags *flag.FlagSet
 }
 
-func handlerLogin(s *state, cmd command) error {
-	if len(cmd.Arguments) < 1 {
-		return fmt.Errorf("username is required")
-	}
+func (cmd command) flagValue(name string) any {
+	return cmd.Flags.Lookup(name).Value.(flag.Getter).Get()
+}
+
+func (cmd command) stringFlag(name string) string {
+	return cmd.flagValue(name).(string)
+}
+
+func (cmd command) boolFlag(name string) bool {
+	return cmd.flagValue(name).(bool)
+}
+
+func (cmd command) intFlag(name string) int {
+	return cmd.flagValue(name).(int)
+}
+
+func (cmd command) durationFlag(name string) time.Duration {
+	return cmd.flagValue(name).(time.Duration)
+}
 
+func handlerLogin(s *state, cmd command) error {
 	username := cmd.Arguments[0]
 
 	// Check if the user exists in the database
@@ -42,26 +91,184 @@ func handlerLogin(s *state, cmd command) error {
 		return fmt.Errorf("failed to set user: %w", err)
 	}
 	
-	fmt.Printf("Username has been set to %v", username)
+	fmt.Printf("Username has been set to %v\n", username)
 
 	return nil
 }
 
+// commandSpec describes a command: how it is invoked, what it accepts and
+// which handler runs it. run validates arguments against it before the
+// handler is called, and help is generated from it.
+type commandSpec struct {
+	Name string
+	// Usage lists the positional arguments, e.g. "<name> <url>"
+	Usage string
+	Summary string
+	// Flags defines the command's flags. Commands with subcommands parse
+	// their own flags, so Flags is only used by commands without them.
+	Flags func(fs *flag.FlagSet)
+	// MinArgs and MaxArgs bound the number of positional arguments; a
+	// negative MaxArgs means no limit
+	MinArgs int
+	MaxArgs int
+	Subcommands []subcommandSpec
+	// NoDatabase commands run without a database, so they work before
+	// gator is configured
+	NoDatabase bool
+	// AnySchema commands run even when the schema isn't up to date
+	AnySchema bool
+	// RawArgs commands get their arguments exactly as given, with no flag
+	// parsing, argument checks or --help handling
+	RawArgs bool
+	// Hidden commands are left out of help and completion
+	Hidden bool
+	// Complete returns candidates for the next positional argument, given
+	// the arguments before it. Candidates are filtered by what has been
+	// typed so far by the caller.
+	Complete func(s *state, args []string) []string
+
+	// Exactly one of Handler and UserHandler is set. UserHandler commands
+	// require a logged in user.
+	Handler func(*state, command) error
+	UserHandler func(*state, command, database.User) error
+}
+
+type subcommandSpec struct {
+	Name string
+	Usage string
+	Summary string
+}
+
+func (spec commandSpec) requiresLogin() bool {
+	return spec.UserHandler != nil
+}
+
+func (spec commandSpec) subcommandNames() []string {
+	names := make([]string, len(spec.Subcommands))
+	for i, sub := range spec.Subcommands {
+		names[i] = sub.Name
+	}
+	return names
+}
+
 type commands struct {
-	Handlers map[string]func(*state, command) error
+	Specs map[string]commandSpec
 }
 
-func (c *commands) register(name string, f func(*state, command) error) {
-	if c.Handlers == nil {
-		c.Handlers = make(map[string]func(*state, command) error)
+func (c *commands) register(spec commandSpec) {
+	if c.Specs == nil {
+		c.Specs = make(map[string]commandSpec)
 	}
-	c.Handlers[name] = f
+	c.Specs[spec.Name] = spec
 }
 
-func (c *commands) run(s *state, cmd command) error {
-	handler, ok := c.Handlers[cmd.Name]
+// names returns the names of all commands that aren't hidden, sorted.
+func (c *commands) names() []string {
+	names := make([]string, 0, len(c.Specs))
+	for name, spec := range c.Specs {
+		if !spec.Hidden {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// lookup finds the named command, suggesting the closest match when there
+// isn't one.
+func (c *commands) lookup(name string) (commandSpec, error) {
+	spec, ok := c.Specs[name]
 	if !ok {
-		return fmt.Errorf("command not found: %s", cmd.Name)
+		return commandSpec{}, fmt.Errorf("unknown command: %s%s (run `gator help` for a list)", name, didYouMean(name, c.names()))
+	}
+	return spec, nil
+}
+
+// run validates cmd's arguments against its spec and calls its handler.
+// With -h or --help among the arguments it prints the command's help
+// instead.
+func (c *commands) run(s *state, cmd command) error {
+	spec, err := c.lookup(cmd.Name)
+	if err != nil {
+		return err
+	}
+
+	if cmd.Context == nil {
+		cmd.Context = context.Background()
+	}
+
+	if wantsHelp(cmd.Arguments) && !spec.RawArgs {
+		printCommandHelp(os.Stdout, spec)
+		return nil
+	}
+
+	cmd, err = spec.parse(cmd)
+	if err != nil {
+		return fmt.Errorf("%s: %w", cmd.Name, err)
+	}
+
+	if spec.requiresLogin() {
+		return middlewareLoggedIn(spec.UserHandler)(s, cmd)
+	}
+	return spec.Handler(s, cmd)
+}
+
+// parse checks cmd's arguments against spec and returns cmd with its flags
+// parsed and only the positional arguments left in Arguments.
+func (spec commandSpec) parse(cmd command) (command, error) {
+	args := cmd.Arguments
+
+	if spec.RawArgs {
+		return cmd, nil
+	}
+
+	if len(spec.Subcommands) > 0 {
+		if len(args) < 1 {
+			return cmd, fmt.Errorf("subcommand is required (%s)", strings.Join(spec.subcommandNames(), ", "))
+		}
+		if !slices.Contains(spec.subcommandNames(), args[0]) {
+			return cmd, fmt.Errorf("unknown subcommand: %s%s", args[0], didYouMean(args[0], spec.subcommandNames()))
+		}
+		return cmd, nil
+	}
+
+	fs := flag.NewFlagSet(spec.Name, flag.ContinueOnError)
+	if spec.Flags != nil {
+		spec.Flags(fs)
+	}
+
+	args, err := parseFlags(fs, args)
+	if err != nil {
+		return cmd, fmt.Errorf("%w (run `gator help %s` for usage)", err, spec.Name)
+	}
+
+	switch {
+	case len(args) < spec.MinArgs:
+		return cmd, fmt.Errorf("not enough arguments (usage: %s)", spec.synopsis())
+	case spec.MaxArgs >= 0 && len(args) > spec.MaxArgs:
+		return cmd, fmt.Errorf("too many arguments (usage: %s)", spec.synopsis())
+	}
+
+	cmd.Arguments = args
+	cmd.Flags = fs
+	return cmd, nil
+}
+
+// parseFlags parses fs against args, allowing flags and positional arguments
+// to be interleaved, and returns the positional arguments in order.
+func parseFlags(fs *flag.FlagSet, args []string) ([]string, error) {
+	fs.SetOutput(io.Discard)
+
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
 	}
-	return handler(s, cmd)
 }
\ No newline at end of file
@@ -0,0 +1,318 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/josequiceno2000/gator/internal/database"
+)
+
+// The completion scripts hand the words typed so far to `gator __complete`,
+// which prints one candidate per line, so that all of the logic lives in
+// gator itself and works the same way in every shell.
+
+const bashCompletion = `# bash completion for gator
+_gator() {
+    local line="${COMP_LINE:0:COMP_POINT}"
+    local -a words
+    read -ra words <<< "$line"
+    [[ "$line" == *[[:space:]] ]] && words+=("")
+    local cur="${words[${#words[@]}-1]}"
+
+    local IFS=$'\n'
+    local -a candidates=($(gator __complete "${words[@]:1}" 2>/dev/null))
+
+    # Bash splits words at colons, so drop the part of URLs it considers
+    # already typed
+    if [[ "$cur" == *:* && "$COMP_WORDBREAKS" == *:* ]]; then
+        local prefix="${cur%"${cur##*:}"}"
+        candidates=("${candidates[@]#"$prefix"}")
+    fi
+    COMPREPLY=("${candidates[@]}")
+}
+complete -o default -F _gator gator
+`
+
+const zshCompletion = `#compdef gator
+# zsh completion for gator
+_gator() {
+    local -a candidates
+    candidates=("${(@f)$(gator __complete "${(@)words[2,CURRENT]}" 2>/dev/null)}")
+    candidates=(${candidates:#})
+    if (( ${#candidates} )); then
+        compadd -a candidates
+    else
+        _files
+    fi
+}
+
+if [ "$funcstack[1]" = "_gator" ]; then
+    _gator "$@"
+else
+    compdef _gator gator
+fi
+`
+
+const fishCompletion = `# fish completion for gator
+function __gator_complete
+    set -l tokens (commandline -opc)
+    set -e tokens[1]
+    gator __complete $tokens (commandline -ct) 2>/dev/null
+end
+
+complete -c gator -f -a '(__gator_complete)'
+complete -c gator -l config -r -F
+`
+
+var completionScripts = map[string]string{
+	"bash": bashCompletion,
+	"zsh":  zshCompletion,
+	"fish": fishCompletion,
+}
+
+func handlerCompletion(s *state, cmd command) error {
+	script, ok := completionScripts[cmd.Arguments[0]]
+	if !ok {
+		return fmt.Errorf("completion: unsupported shell: %s (bash, zsh or fish)", cmd.Arguments[0])
+	}
+	fmt.Print(script)
+	return nil
+}
+
+func completeShells(s *state, args []string) []string {
+	if len(args) > 0 {
+		return nil
+	}
+	return []string{"bash", "fish", "zsh"}
+}
+
+// handlerComplete prints completions for the last of its arguments, which
+// the completion scripts pass as the words typed after "gator".
+func handlerComplete(c *commands) func(*state, command) error {
+	return func(s *state, cmd command) error {
+		if len(cmd.Arguments) == 0 {
+			return nil
+		}
+
+		for _, candidate := range completeWords(c, s, cmd.Arguments) {
+			fmt.Println(candidate)
+		}
+		return nil
+	}
+}
+
+// completeWords returns the candidates for the last word in words that
+// start with what has been typed of it.
+func completeWords(c *commands, s *state, words []string) []string {
+	cur := words[len(words)-1]
+	before := words[:len(words)-1]
+
+	var candidates []string
+	for _, candidate := range completeCandidates(c, s, before, cur) {
+		if strings.HasPrefix(candidate, cur) {
+			candidates = append(candidates, candidate)
+		}
+	}
+	return candidates
+}
+
+func completeCandidates(c *commands, s *state, before []string, cur string) []string {
+	// Skip the global flags ahead of the command name
+	i := 0
+	for i < len(before) && strings.HasPrefix(before[i], "-") {
+		if takesValue(before[i]) {
+			i++
+		}
+		i++
+	}
+	if i > len(before) {
+		// cur is the value of the last global flag
+		if flagName(before[len(before)-1]) == "profile" {
+			return s.CfgPointer.Profiles()
+		}
+		return nil
+	}
+	if i == len(before) {
+		if strings.HasPrefix(cur, "-") {
+			return []string{"--config", "--profile"}
+		}
+		return c.names()
+	}
+
+	spec, ok := c.Specs[before[i]]
+	if !ok || spec.Hidden {
+		return nil
+	}
+	args := before[i+1:]
+
+	if strings.HasPrefix(cur, "-") {
+		return completeFlags(spec)
+	}
+	if len(spec.Subcommands) > 0 && len(args) == 0 {
+		return spec.subcommandNames()
+	}
+	if spec.Complete == nil {
+		return nil
+	}
+	return spec.Complete(s, args)
+}
+
+// takesValue reports whether the global flag arg needs the next word as its
+// value.
+func takesValue(arg string) bool {
+	return !strings.Contains(arg, "=") && slices.Contains([]string{"config", "profile"}, flagName(arg))
+}
+
+func flagName(arg string) string {
+	name, _, _ := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+	return name
+}
+
+func completeFlags(spec commandSpec) []string {
+	flags := []string{"--help"}
+	if spec.Flags == nil {
+		return flags
+	}
+
+	fs := flag.NewFlagSet(spec.Name, flag.ContinueOnError)
+	spec.Flags(fs)
+	fs.VisitAll(func(f *flag.Flag) {
+		flags = append(flags, "--"+f.Name)
+	})
+	return flags
+}
+
+// The completers below query the database, which may be unconfigured or
+// unreachable while the user is typing; they offer nothing rather than
+// printing errors into the shell.
+
+func completeUsers(s *state, args []string) []string {
+	if len(args) > 0 || s.DB == nil {
+		return nil
+	}
+
+	users, err := s.DB.GetUsers(context.Background())
+	if err != nil {
+		return nil
+	}
+	return users
+}
+
+func completeFeedURLs(s *state, args []string) []string {
+	if len(args) > 0 || s.DB == nil {
+		return nil
+	}
+
+	feeds, err := s.DB.GetFeedsWithUserNames(context.Background())
+	if err != nil {
+		return nil
+	}
+
+	urls := make([]string, len(feeds))
+	for i, feed := range feeds {
+		urls[i] = feed.Url
+	}
+	return urls
+}
+
+func completeFollowedURLs(s *state, args []string) []string {
+	if len(args) > 0 {
+		return nil
+	}
+	return followedURLs(s)
+}
+
+// completeFolder completes folder names, and followed feed URLs where a
+// folder subcommand takes one.
+func completeFolder(s *state, args []string) []string {
+	switch {
+	case len(args) == 1:
+		return folderNames(s)
+	case len(args) == 2 && (args[0] == "add" || args[0] == "rm"):
+		return followedURLs(s)
+	}
+	return nil
+}
+
+// completeBrowse completes the value of --folder.
+func completeBrowse(s *state, args []string) []string {
+	if len(args) > 0 && flagName(args[len(args)-1]) == "folder" && !strings.Contains(args[len(args)-1], "=") {
+		return folderNames(s)
+	}
+	return nil
+}
+
+func completeProfile(s *state, args []string) []string {
+	if len(args) == 1 && (args[0] == "use" || args[0] == "rm") {
+		return s.CfgPointer.Profiles()
+	}
+	return nil
+}
+
+func completeToken(s *state, args []string) []string {
+	if len(args) != 1 || args[0] != "rm" {
+		return nil
+	}
+
+	user, ok := currentUser(s)
+	if !ok {
+		return nil
+	}
+	tokens, err := s.DB.GetAPITokensForUser(context.Background(), user.ID)
+	if err != nil {
+		return nil
+	}
+
+	var names []string
+	for _, token := range tokens {
+		names = append(names, token.Name)
+	}
+	return names
+}
+
+func followedURLs(s *state) []string {
+	var urls []string
+	for _, ff := range currentFeedFollows(s) {
+		urls = append(urls, ff.FeedUrl)
+	}
+	return urls
+}
+
+func folderNames(s *state) []string {
+	seen := make(map[string]bool)
+	var folders []string
+	for _, ff := range currentFeedFollows(s) {
+		if ff.Folder.Valid && !seen[ff.Folder.String] {
+			seen[ff.Folder.String] = true
+			folders = append(folders, ff.Folder.String)
+		}
+	}
+	sort.Strings(folders)
+	return folders
+}
+
+func currentUser(s *state) (database.User, bool) {
+	if s.DB == nil || s.CfgPointer.CurrentUsername == "" {
+		return database.User{}, false
+	}
+
+	user, err := s.DB.GetUser(context.Background(), s.CfgPointer.CurrentUsername)
+	return user, err == nil
+}
+
+func currentFeedFollows(s *state) []database.GetFeedFollowsForUserRow {
+	user, ok := currentUser(s)
+	if !ok {
+		return nil
+	}
+
+	feedFollows, err := s.DB.GetFeedFollowsForUser(context.Background(), user.ID)
+	if err != nil {
+		return nil
+	}
+	return feedFollows
+}
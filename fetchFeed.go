@@ -26,6 +26,17 @@ type RSSItem struct {
 	Link string `xml:"link"`
 	Description string `xml:"description"`
 	PubDate string `xml:"pubDate"`
+	Author string `xml:"author"`
+	Creator string `xml:"http://purl.org/dc/elements/1.1/ creator"`
+}
+
+// author prefers the RSS author element and falls back to dc:creator,
+// which most feeds use instead since RSS expects an email address.
+func (item RSSItem) author() string {
+	if item.Author != "" {
+		return item.Author
+	}
+	return item.Creator
 }
 
 type FeedwithUsername struct {
@@ -45,13 +56,18 @@ func fetchFeed(ctx context.Context, feedURL string) (*RSSFeed, error) {
 
 	req.Header.Set("User-Agent", "gator")
 
+	start := time.Now()
 	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
+		fetchesTotal.Inc("error")
 		return nil, fmt.Errorf("fetchFeed: failed to do request: %w", err)
 	}
 	defer resp.Body.Close()
+	fetchesTotal.Inc(statusClass(resp.StatusCode))
 
 	body, err := io.ReadAll(resp.Body)
+	fetchBytes.Add(float64(len(body)))
+	fetchDuration.Observe(time.Since(start).Seconds())
 	if err != nil {
 		return nil, fmt.Errorf("fetchFeed: failed to read response body: %w", err)
 	}
@@ -59,6 +75,7 @@ func fetchFeed(ctx context.Context, feedURL string) (*RSSFeed, error) {
 	var rssFeed RSSFeed
 	err = xml.Unmarshal(body, &rssFeed)
 	if err != nil {
+		parseErrors.Inc()
 		return nil, fmt.Errorf("fetchFeed: failed to unmarshal XML: %w", err)
 	}
 
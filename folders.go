@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/josequiceno2000/gator/internal/database"
+)
+
+func handlerFolder(s *state, cmd command, user database.User) error {
+	args := cmd.Arguments[1:]
+
+	switch cmd.Arguments[0] {
+	case "add":
+		if len(args) < 2 {
+			return errors.New("folder add: folder and url arguments are required")
+		}
+		folder, url := args[0], args[1]
+		if strings.TrimSpace(folder) == "" {
+			return errors.New("folder add: folder name can't be blank (usage: gator folder add <folder> <url>)")
+		}
+
+		n, err := s.DB.SetFeedFollowFolder(context.Background(), database.SetFeedFollowFolderParams{
+			UserID: user.ID,
+			Url:    url,
+			Folder: sql.NullString{String: folder, Valid: true},
+		})
+		if err != nil {
+			return fmt.Errorf("folder add: failed to set folder: %w", err)
+		}
+		if n == 0 {
+			return fmt.Errorf("folder add: not following feed with URL: %s", url)
+		}
+
+		fmt.Printf("Moved %s into folder: %s\n", url, folder)
+	case "rm":
+		if len(args) < 1 {
+			return errors.New("folder rm: folder argument is required")
+		}
+		folder := args[0]
+
+		var url sql.NullString
+		if len(args) > 1 {
+			url = sql.NullString{String: args[1], Valid: true}
+		}
+
+		n, err := s.DB.ClearFolder(context.Background(), database.ClearFolderParams{
+			UserID: user.ID,
+			Folder: folder,
+			Url:    url,
+		})
+		if err != nil {
+			return fmt.Errorf("folder rm: failed to clear folder: %w", err)
+		}
+		if n == 0 {
+			return fmt.Errorf("folder rm: no matching feeds in folder: %s", folder)
+		}
+
+		fmt.Printf("Removed %d feed(s) from folder: %s\n", n, folder)
+	case "mv":
+		if len(args) < 2 {
+			return errors.New("folder mv: old and new folder arguments are required")
+		}
+		oldFolder, newFolder := args[0], args[1]
+		if strings.TrimSpace(newFolder) == "" {
+			return errors.New("folder mv: new folder name can't be blank (usage: gator folder mv <old> <new>)")
+		}
+
+		n, err := s.DB.RenameFolder(context.Background(), database.RenameFolderParams{
+			NewFolder: newFolder,
+			UserID:    user.ID,
+			OldFolder: oldFolder,
+		})
+		if err != nil {
+			return fmt.Errorf("folder mv: failed to rename folder: %w", err)
+		}
+		if n == 0 {
+			return fmt.Errorf("folder mv: folder does not exist: %s", oldFolder)
+		}
+
+		fmt.Printf("Renamed folder %s to %s\n", oldFolder, newFolder)
+	default:
+		return fmt.Errorf("folder: unknown subcommand: %s", cmd.Arguments[0])
+	}
+
+	return nil
+}
+
+// printFollowingTree prints a user's followed feeds grouped by folder, with
+// unfiled feeds listed last.
+func printFollowingTree(feedFollows []database.GetFeedFollowsForUserRow) {
+	groups := make(map[string][]string)
+	var unfiled []string
+
+	for _, ff := range feedFollows {
+		if !ff.Folder.Valid {
+			unfiled = append(unfiled, ff.FeedName)
+			continue
+		}
+		groups[ff.Folder.String] = append(groups[ff.Folder.String], ff.FeedName)
+	}
+
+	folders := make([]string, 0, len(groups))
+	for folder := range groups {
+		folders = append(folders, folder)
+	}
+	sort.Strings(folders)
+
+	for _, folder := range folders {
+		fmt.Printf("%s/\n", folder)
+		for _, name := range groups[folder] {
+			fmt.Printf("  %s\n", name)
+		}
+	}
+
+	for _, name := range unfiled {
+		fmt.Println(name)
+	}
+}
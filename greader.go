@@ -0,0 +1,582 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/josequiceno2000/gator/internal/database"
+)
+
+// The subset of the Google Reader API spoken by clients such as Reeder and
+// NetNewsWire (often under the name FreshRSS), served by `gator serve`
+// next to the JSON API. Clients log in with a username and an API token
+// as the password, then send the token back as
+// "Authorization: GoogleLogin auth=<token>".
+//
+// Feeds are streams named feed/<id>, folders are labels and saved posts
+// are starred. Google Reader identifies items by 64-bit integers, so each
+// post's is made from the first bits of its UUID; see readerItemID.
+
+const (
+	readerPrefix = "/reader/api/0"
+
+	readingListStream = "user/-/state/com.google/reading-list"
+	readStream        = "user/-/state/com.google/read"
+	starredStream     = "user/-/state/com.google/starred"
+	keptUnreadStream  = "user/-/state/com.google/kept-unread"
+	labelStreamPrefix = "user/-/label/"
+	feedStreamPrefix  = "feed/"
+
+	readerItemPrefix = "tag:google.com,2005:reader/item/"
+
+	defaultReaderItems = 20
+	maxReaderItems     = 1000
+	maxReaderItemIDs   = 10000
+)
+
+type readerServer struct {
+	*apiServer
+}
+
+func registerReaderAPI(mux *http.ServeMux, s *state) {
+	rd := &readerServer{&apiServer{s: s}}
+
+	mux.HandleFunc("/accounts/ClientLogin", rd.clientLogin)
+
+	mux.Handle("GET "+readerPrefix+"/token", rd.auth(rd.token))
+	mux.Handle("GET "+readerPrefix+"/user-info", rd.auth(rd.userInfo))
+	mux.Handle("GET "+readerPrefix+"/subscription/list", rd.auth(rd.subscriptionList))
+	mux.Handle("GET "+readerPrefix+"/tag/list", rd.auth(rd.tagList))
+	mux.Handle("GET "+readerPrefix+"/unread-count", rd.auth(rd.unreadCount))
+	mux.Handle("GET "+readerPrefix+"/stream/contents", rd.auth(rd.streamContents))
+	mux.Handle("GET "+readerPrefix+"/stream/contents/{stream...}", rd.auth(rd.streamContents))
+	mux.Handle(readerPrefix+"/stream/items/ids", rd.auth(rd.streamItemIDs))
+	mux.Handle(readerPrefix+"/stream/items/contents", rd.auth(rd.streamItemContents))
+	mux.Handle("POST "+readerPrefix+"/edit-tag", rd.auth(rd.editTag))
+}
+
+// clientLogin checks a username and API token, sent as Email and Passwd,
+// and answers with the token as the Auth value clients send back.
+func (rd *readerServer) clientLogin(w http.ResponseWriter, r *http.Request) {
+	name, token := r.FormValue("Email"), r.FormValue("Passwd")
+
+	user, err := userForAPIToken(r.Context(), rd.s, token)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		rd.internalError(w, r, err)
+		return
+	}
+	if err != nil || user.Name != name {
+		http.Error(w, "Error=BadAuthentication", http.StatusUnauthorized)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	fmt.Fprintf(w, "SID=%s\nLSID=%s\nAuth=%s\n", token, token, token)
+}
+
+// auth resolves the GoogleLogin token to a user before calling next.
+func (rd *readerServer) auth(next apiHandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "GoogleLogin auth=")
+		if !ok || token == "" {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		user, err := userForAPIToken(r.Context(), rd.s, token)
+		if errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if err != nil {
+			rd.internalError(w, r, err)
+			return
+		}
+
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, "invalid form: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		next(w, r, user)
+	})
+}
+
+// token returns the edit token clients pass as T. Requests are
+// authenticated by header rather than cookie, so it isn't checked.
+func (rd *readerServer) token(w http.ResponseWriter, r *http.Request, user database.User) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	fmt.Fprintln(w, strings.ReplaceAll(user.ID.String(), "-", ""))
+}
+
+func (rd *readerServer) userInfo(w http.ResponseWriter, r *http.Request, user database.User) {
+	writeJSON(w, http.StatusOK, map[string]string{
+		"userId":        user.ID.String(),
+		"userName":      user.Name,
+		"userProfileId": user.ID.String(),
+		"userEmail":     "",
+	})
+}
+
+type readerCategory struct {
+	ID    string `json:"id"`
+	Label string `json:"label"`
+}
+
+type readerSubscription struct {
+	ID         string           `json:"id"`
+	Title      string           `json:"title"`
+	Categories []readerCategory `json:"categories"`
+	URL        string           `json:"url"`
+}
+
+func labelStream(folder string) string {
+	return labelStreamPrefix + folder
+}
+
+func feedStream(feedID uuid.UUID) string {
+	return feedStreamPrefix + feedID.String()
+}
+
+// followTitle is the name user sees for a followed feed: the one they
+// gave it, if any, or else the feed's own.
+func followTitle(ff database.GetFeedFollowsForUserRow) string {
+	if ff.DisplayName.Valid {
+		return ff.DisplayName.String
+	}
+	return ff.FeedName
+}
+
+func (rd *readerServer) subscriptionList(w http.ResponseWriter, r *http.Request, user database.User) {
+	follows, err := rd.s.DB.GetFeedFollowsForUser(r.Context(), user.ID)
+	if err != nil {
+		rd.internalError(w, r, err)
+		return
+	}
+
+	subs := []readerSubscription{}
+	for _, ff := range follows {
+		sub := readerSubscription{
+			ID:         feedStream(ff.FeedID),
+			Title:      followTitle(ff),
+			Categories: []readerCategory{},
+			URL:        ff.FeedUrl,
+		}
+		if ff.Folder.Valid {
+			sub.Categories = append(sub.Categories, readerCategory{ID: labelStream(ff.Folder.String), Label: ff.Folder.String})
+		}
+		subs = append(subs, sub)
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"subscriptions": subs})
+}
+
+func (rd *readerServer) tagList(w http.ResponseWriter, r *http.Request, user database.User) {
+	follows, err := rd.s.DB.GetFeedFollowsForUser(r.Context(), user.ID)
+	if err != nil {
+		rd.internalError(w, r, err)
+		return
+	}
+
+	tags := []map[string]string{{"id": starredStream}}
+	seen := make(map[string]bool)
+	for _, ff := range follows {
+		if ff.Folder.Valid && !seen[ff.Folder.String] {
+			seen[ff.Folder.String] = true
+			tags = append(tags, map[string]string{"id": labelStream(ff.Folder.String), "type": "folder"})
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"tags": tags})
+}
+
+type readerUnreadCount struct {
+	ID    string `json:"id"`
+	Count int64  `json:"count"`
+}
+
+// unreadCount reports unread posts for each followed feed, each folder and
+// the reading list as a whole.
+func (rd *readerServer) unreadCount(w http.ResponseWriter, r *http.Request, user database.User) {
+	rows, err := rd.s.DB.GetUnreadCountsForUser(r.Context(), user.ID)
+	if err != nil {
+		rd.internalError(w, r, err)
+		return
+	}
+	follows, err := rd.s.DB.GetFeedFollowsForUser(r.Context(), user.ID)
+	if err != nil {
+		rd.internalError(w, r, err)
+		return
+	}
+
+	folderOf := make(map[uuid.UUID]string)
+	for _, ff := range follows {
+		if ff.Folder.Valid {
+			folderOf[ff.FeedID] = ff.Folder.String
+		}
+	}
+
+	counts := []readerUnreadCount{}
+	byFolder := make(map[string]int64)
+	var folders []string
+	var total int64
+	for _, row := range rows {
+		counts = append(counts, readerUnreadCount{ID: feedStream(row.FeedID), Count: row.Unread})
+		total += row.Unread
+		if folder, ok := folderOf[row.FeedID]; ok {
+			if _, seen := byFolder[folder]; !seen {
+				folders = append(folders, folder)
+			}
+			byFolder[folder] += row.Unread
+		}
+	}
+	for _, folder := range folders {
+		counts = append(counts, readerUnreadCount{ID: labelStream(folder), Count: byFolder[folder]})
+	}
+	counts = append(counts, readerUnreadCount{ID: readingListStream, Count: total})
+
+	writeJSON(w, http.StatusOK, map[string]any{"max": total, "unreadcounts": counts})
+}
+
+// normalizeStream replaces the user ID in user/<id>/... streams with "-",
+// which clients may use interchangeably.
+func normalizeStream(stream string) string {
+	if rest, ok := strings.CutPrefix(stream, "user/"); ok {
+		if _, after, found := strings.Cut(rest, "/"); found {
+			return "user/-/" + after
+		}
+	}
+	return stream
+}
+
+// streamQuery turns the stream and filters of a stream request into
+// GetPostsForUser parameters, with limit and offset left for the caller.
+func streamQuery(r *http.Request, user database.User) (database.GetPostsForUserParams, string, error) {
+	params := database.GetPostsForUserParams{UserID: user.ID}
+
+	stream := r.PathValue("stream")
+	if stream == "" {
+		stream = r.Form.Get("s")
+	}
+	if stream == "" {
+		stream = readingListStream
+	}
+	stream = normalizeStream(stream)
+
+	switch {
+	case stream == readingListStream:
+	case stream == starredStream:
+		params.SavedOnly = true
+	case strings.HasPrefix(stream, labelStreamPrefix):
+		params.Folder = sql.NullString{String: strings.TrimPrefix(stream, labelStreamPrefix), Valid: true}
+	case strings.HasPrefix(stream, feedStreamPrefix):
+		id, err := uuid.Parse(strings.TrimPrefix(stream, feedStreamPrefix))
+		if err != nil {
+			return params, stream, fmt.Errorf("unknown feed: %s", stream)
+		}
+		params.FeedID = uuid.NullUUID{UUID: id, Valid: true}
+	default:
+		return params, stream, fmt.Errorf("unsupported stream: %s", stream)
+	}
+
+	switch xt := normalizeStream(r.Form.Get("xt")); xt {
+	case "":
+	case readStream:
+		params.UnreadOnly = true
+	default:
+		return params, stream, fmt.Errorf("unsupported exclude target: %s", xt)
+	}
+	switch it := normalizeStream(r.Form.Get("it")); it {
+	case "":
+	case starredStream:
+		params.SavedOnly = true
+	default:
+		return params, stream, fmt.Errorf("unsupported include target: %s", it)
+	}
+
+	if ot := r.Form.Get("ot"); ot != "" {
+		secs, err := strconv.ParseInt(ot, 10, 64)
+		if err != nil {
+			return params, stream, errors.New("ot: must be a unix time")
+		}
+		params.PublishedAfter = sql.NullTime{Time: time.Unix(secs, 0).UTC(), Valid: true}
+	}
+	params.OldestFirst = r.Form.Get("r") == "o"
+
+	return params, stream, nil
+}
+
+// streamPage runs a stream request for n posts, capped at maxItems,
+// starting at the continuation c. It returns the posts, the stream and the
+// continuation of the next page, if any.
+func (rd *readerServer) streamPage(w http.ResponseWriter, r *http.Request, user database.User, maxItems int) ([]database.GetPostsForUserRow, string, string, bool) {
+	params, stream, err := streamQuery(r, user)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil, "", "", false
+	}
+
+	limit := defaultReaderItems
+	if n, err := strconv.Atoi(r.Form.Get("n")); err == nil && n > 0 {
+		limit = min(n, maxItems)
+	}
+	offset := 0
+	if c := r.Form.Get("c"); c != "" {
+		offset, err = strconv.Atoi(c)
+		if err != nil || offset < 0 {
+			http.Error(w, "invalid continuation", http.StatusBadRequest)
+			return nil, "", "", false
+		}
+	}
+	// One extra post tells whether there is another page
+	params.Limit = int32(limit + 1)
+	params.Offset = int32(offset)
+
+	rows, err := rd.s.DB.GetPostsForUser(r.Context(), params)
+	if err != nil {
+		rd.internalError(w, r, err)
+		return nil, "", "", false
+	}
+
+	var continuation string
+	if len(rows) > limit {
+		rows = rows[:limit]
+		continuation = strconv.Itoa(offset + limit)
+	}
+	return rows, stream, continuation, true
+}
+
+// readerItemID is the Google Reader ID of a post: the top 63 bits of its
+// UUID, kept positive since clients store IDs as signed integers. Post
+// UUIDs are random, so two posts sharing one is vanishingly unlikely.
+func readerItemID(id uuid.UUID) int64 {
+	return int64(binary.BigEndian.Uint64(id[:8]) >> 1)
+}
+
+// parseReaderItemID accepts both the long form, with its ID in hex, and
+// the short decimal form of an item ID.
+func parseReaderItemID(s string) (int64, error) {
+	if hex, ok := strings.CutPrefix(s, readerItemPrefix); ok {
+		n, err := strconv.ParseUint(hex, 16, 64)
+		return int64(n), err
+	}
+	return strconv.ParseInt(s, 10, 64)
+}
+
+// lookupReaderItem returns the post with the given Google Reader ID if
+// user follows its feed, or sql.ErrNoRows.
+func (rd *readerServer) lookupReaderItem(r *http.Request, user database.User, item string) (database.GetPostForUserRow, error) {
+	n, err := parseReaderItemID(item)
+	if err != nil || n < 0 {
+		return database.GetPostForUserRow{}, sql.ErrNoRows
+	}
+
+	var from, to uuid.UUID
+	binary.BigEndian.PutUint64(from[:8], uint64(n)<<1)
+	binary.BigEndian.PutUint64(to[:8], uint64(n)<<1|1)
+	binary.BigEndian.PutUint64(to[8:], ^uint64(0))
+
+	id, err := rd.s.DB.GetPostIDInRange(r.Context(), database.GetPostIDInRangeParams{IDFrom: from, IDTo: to})
+	if err != nil {
+		return database.GetPostForUserRow{}, err
+	}
+	return rd.s.DB.GetPostForUser(r.Context(), database.GetPostForUserParams{UserID: user.ID, ID: id})
+}
+
+type readerLink struct {
+	Href string `json:"href"`
+	Type string `json:"type,omitempty"`
+}
+
+type readerItem struct {
+	ID            string            `json:"id"`
+	CrawlTimeMsec string            `json:"crawlTimeMsec"`
+	TimestampUsec string            `json:"timestampUsec"`
+	Published     int64             `json:"published"`
+	Updated       int64             `json:"updated"`
+	Title         string            `json:"title"`
+	Canonical     []readerLink      `json:"canonical"`
+	Alternate     []readerLink      `json:"alternate"`
+	Summary       map[string]string `json:"summary"`
+	Author        string            `json:"author,omitempty"`
+	Categories    []string          `json:"categories"`
+	Origin        map[string]string `json:"origin"`
+}
+
+func toReaderItem(row database.GetPostsForUserRow, follows map[uuid.UUID]database.GetFeedFollowsForUserRow) readerItem {
+	ff := follows[row.FeedID]
+	item := readerItem{
+		ID:            fmt.Sprintf("%s%016x", readerItemPrefix, readerItemID(row.ID)),
+		CrawlTimeMsec: strconv.FormatInt(row.CreatedAt.UnixMilli(), 10),
+		TimestampUsec: strconv.FormatInt(row.CreatedAt.UnixMicro(), 10),
+		Published:     row.PublishedAt.Unix(),
+		Updated:       row.PublishedAt.Unix(),
+		Title:         row.Title,
+		Canonical:     []readerLink{{Href: row.Url}},
+		Alternate:     []readerLink{{Href: row.Url, Type: "text/html"}},
+		Summary:       map[string]string{"direction": "ltr", "content": row.Description.String},
+		Author:        row.Author.String,
+		Categories:    []string{readingListStream},
+		Origin: map[string]string{
+			"streamId": feedStream(row.FeedID),
+			"title":    followTitle(ff),
+			"htmlUrl":  ff.FeedUrl,
+		},
+	}
+	if row.ReadAt.Valid {
+		item.Categories = append(item.Categories, readStream)
+	}
+	if row.SavedAt.Valid {
+		item.Categories = append(item.Categories, starredStream)
+	}
+	if ff.Folder.Valid {
+		item.Categories = append(item.Categories, labelStream(ff.Folder.String))
+	}
+	return item
+}
+
+// writeReaderItems answers a contents request with rows in the stream
+// format.
+func (rd *readerServer) writeReaderItems(w http.ResponseWriter, r *http.Request, user database.User, stream string, rows []database.GetPostsForUserRow, continuation string) {
+	followRows, err := rd.s.DB.GetFeedFollowsForUser(r.Context(), user.ID)
+	if err != nil {
+		rd.internalError(w, r, err)
+		return
+	}
+	follows := make(map[uuid.UUID]database.GetFeedFollowsForUserRow)
+	for _, ff := range followRows {
+		follows[ff.FeedID] = ff
+	}
+
+	items := []readerItem{}
+	for _, row := range rows {
+		items = append(items, toReaderItem(row, follows))
+	}
+
+	resp := map[string]any{
+		"direction": "ltr",
+		"id":        stream,
+		"updated":   time.Now().Unix(),
+		"items":     items,
+	}
+	if continuation != "" {
+		resp["continuation"] = continuation
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (rd *readerServer) streamContents(w http.ResponseWriter, r *http.Request, user database.User) {
+	rows, stream, continuation, ok := rd.streamPage(w, r, user, maxReaderItems)
+	if !ok {
+		return
+	}
+	rd.writeReaderItems(w, r, user, stream, rows, continuation)
+}
+
+type readerItemRef struct {
+	ID              string   `json:"id"`
+	DirectStreamIDs []string `json:"directStreamIds"`
+	TimestampUsec   string   `json:"timestampUsec"`
+}
+
+func (rd *readerServer) streamItemIDs(w http.ResponseWriter, r *http.Request, user database.User) {
+	rows, _, continuation, ok := rd.streamPage(w, r, user, maxReaderItemIDs)
+	if !ok {
+		return
+	}
+
+	refs := []readerItemRef{}
+	for _, row := range rows {
+		refs = append(refs, readerItemRef{
+			ID:              strconv.FormatInt(readerItemID(row.ID), 10),
+			DirectStreamIDs: []string{},
+			TimestampUsec:   strconv.FormatInt(row.CreatedAt.UnixMicro(), 10),
+		})
+	}
+
+	resp := map[string]any{"itemRefs": refs}
+	if continuation != "" {
+		resp["continuation"] = continuation
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// streamItemContents returns the posts listed by i parameters, skipping
+// ones that don't exist or aren't in a followed feed.
+func (rd *readerServer) streamItemContents(w http.ResponseWriter, r *http.Request, user database.User) {
+	var rows []database.GetPostsForUserRow
+	for _, item := range r.Form["i"] {
+		post, err := rd.lookupReaderItem(r, user, item)
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			rd.internalError(w, r, err)
+			return
+		}
+		rows = append(rows, database.GetPostsForUserRow(post))
+	}
+	rd.writeReaderItems(w, r, user, readingListStream, rows, "")
+}
+
+// editTag adds (a) and removes (r) the read and starred states of the
+// posts listed by i parameters. Other tags, such as labels, are ignored.
+func (rd *readerServer) editTag(w http.ResponseWriter, r *http.Request, user database.User) {
+	type change struct {
+		read, set bool
+	}
+	var changes []change
+	for _, tag := range r.Form["a"] {
+		switch normalizeStream(tag) {
+		case readStream:
+			changes = append(changes, change{read: true, set: true})
+		case keptUnreadStream:
+			changes = append(changes, change{read: true, set: false})
+		case starredStream:
+			changes = append(changes, change{read: false, set: true})
+		}
+	}
+	for _, tag := range r.Form["r"] {
+		switch normalizeStream(tag) {
+		case readStream:
+			changes = append(changes, change{read: true, set: false})
+		case starredStream:
+			changes = append(changes, change{read: false, set: false})
+		}
+	}
+
+	var posts []uuid.UUID
+	for _, item := range r.Form["i"] {
+		post, err := rd.lookupReaderItem(r, user, item)
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			rd.internalError(w, r, err)
+			return
+		}
+		posts = append(posts, post.ID)
+	}
+
+	err := rd.s.withTx(r.Context(), func(q database.Querier) error {
+		for _, postID := range posts {
+			for _, c := range changes {
+				if err := setPostState(r.Context(), q, user.ID, postID, c.read, c.set); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		rd.internalError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	fmt.Fprint(w, "OK")
+}
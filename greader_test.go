@@ -0,0 +1,390 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/josequiceno2000/gator/internal/database"
+	"github.com/josequiceno2000/gator/internal/urlnorm"
+)
+
+// readerRequest sends a Google Reader API request with form as the query
+// string, or as the body of a POST, and returns the response status and
+// body. An empty auth sends no Authorization header.
+func (f *apiFixture) readerRequest(t *testing.T, method, path, auth string, form url.Values) (int, []byte) {
+	t.Helper()
+
+	target := f.srv.URL + path
+	var body io.Reader
+	if method == http.MethodPost {
+		body = strings.NewReader(form.Encode())
+	} else if len(form) > 0 {
+		target += "?" + form.Encode()
+	}
+
+	req, err := http.NewRequest(method, target, body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if method == http.MethodPost {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	if auth != "" {
+		req.Header.Set("Authorization", "GoogleLogin auth="+auth)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp.StatusCode, data
+}
+
+// readerGet sends a GET request expected to succeed and decodes its JSON
+// body into v.
+func (f *apiFixture) readerGet(t *testing.T, path, auth string, form url.Values, v any) {
+	t.Helper()
+	code, body := f.readerRequest(t, http.MethodGet, path, auth, form)
+	if code != http.StatusOK {
+		t.Fatalf("GET %s?%s: status %d: %s", path, form.Encode(), code, body)
+	}
+	if err := json.Unmarshal(body, v); err != nil {
+		t.Fatalf("GET %s: %v: %s", path, err, body)
+	}
+}
+
+type readerStream struct {
+	Items        []readerItem `json:"items"`
+	Continuation string       `json:"continuation"`
+}
+
+func (f *apiFixture) readerItems(t *testing.T, auth string, form url.Values) readerStream {
+	t.Helper()
+	var stream readerStream
+	f.readerGet(t, readerPrefix+"/stream/contents", auth, form, &stream)
+	return stream
+}
+
+func readerTitles(stream readerStream) []string {
+	titles := make([]string, len(stream.Items))
+	for i, item := range stream.Items {
+		titles[i] = item.Title
+	}
+	return titles
+}
+
+func (f *apiFixture) editTag(t *testing.T, auth string, form url.Values) {
+	t.Helper()
+	code, body := f.readerRequest(t, http.MethodPost, readerPrefix+"/edit-tag", auth, form)
+	if code != http.StatusOK || string(body) != "OK" {
+		t.Fatalf("edit-tag %s: status %d: %s", form.Encode(), code, body)
+	}
+}
+
+func TestReaderItemID(t *testing.T) {
+	tests := []struct {
+		id   string
+		want int64
+	}{
+		{"00000000-0000-0000-0000-000000000000", 0},
+		{"00000000-0000-0001-ffff-ffffffffffff", 0},
+		{"00000000-0000-0002-0000-000000000000", 1},
+		{"00000000-0000-0003-0000-000000000000", 1},
+		{"00000000-0000-0054-0000-000000000000", 42},
+		{"ffffffff-ffff-ffff-0000-000000000000", 1<<63 - 1},
+	}
+	for _, tt := range tests {
+		if got := readerItemID(uuid.MustParse(tt.id)); got != tt.want {
+			t.Errorf("readerItemID(%s) = %d, want %d", tt.id, got, tt.want)
+		}
+	}
+}
+
+func TestParseReaderItemID(t *testing.T) {
+	tests := []struct {
+		s       string
+		want    int64
+		wantErr bool
+	}{
+		{"42", 42, false},
+		{readerItemPrefix + "000000000000002a", 42, false},
+		{readerItemPrefix + "2a", 42, false},
+		{readerItemPrefix + "7fffffffffffffff", 1<<63 - 1, false},
+		{"9223372036854775807", 1<<63 - 1, false},
+		{readerItemPrefix + "42", 66, false}, // hex, not decimal
+		{"2a", 0, true},
+		{readerItemPrefix + "xyz", 0, true},
+		{readerItemPrefix + "10000000000000000", 0, true},
+		{"", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := parseReaderItemID(tt.s)
+		if (err != nil) != tt.wantErr || (!tt.wantErr && got != tt.want) {
+			t.Errorf("parseReaderItemID(%q) = %d, %v; want %d, error %t", tt.s, got, err, tt.want, tt.wantErr)
+		}
+	}
+
+	// Both forms of a post's ID lead back to it
+	id := uuid.New()
+	n := readerItemID(id)
+	for _, s := range []string{fmt.Sprintf("%s%016x", readerItemPrefix, n), strconv.FormatInt(n, 10)} {
+		if got, err := parseReaderItemID(s); err != nil || got != n {
+			t.Errorf("parseReaderItemID(%q) = %d, %v; want %d", s, got, err, n)
+		}
+	}
+}
+
+func TestReaderClientLogin(t *testing.T) {
+	f := newAPIFixture(t)
+
+	login := func(email, passwd string) (int, string) {
+		t.Helper()
+		resp, err := http.PostForm(f.srv.URL+"/accounts/ClientLogin", url.Values{"Email": {email}, "Passwd": {passwd}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(body)
+	}
+
+	code, body := login("alice", f.alice)
+	if code != http.StatusOK || !strings.Contains(body, "\nAuth="+f.alice+"\n") {
+		t.Errorf("ClientLogin: status %d: %q", code, body)
+	}
+	for _, tt := range []struct{ email, passwd string }{
+		{"alice", "wrong"},
+		{"bob", f.alice},
+		{"alice", ""},
+	} {
+		if code, body := login(tt.email, tt.passwd); code != http.StatusUnauthorized || !strings.Contains(body, "BadAuthentication") {
+			t.Errorf("ClientLogin as %q with %q: status %d: %q", tt.email, tt.passwd, code, body)
+		}
+	}
+
+	for _, auth := range []string{"", apiTokenPrefix + "0000"} {
+		if code, _ := f.readerRequest(t, http.MethodGet, readerPrefix+"/user-info", auth, nil); code != http.StatusUnauthorized {
+			t.Errorf("user-info with auth %q: status = %d, want 401", auth, code)
+		}
+	}
+
+	var info map[string]string
+	f.readerGet(t, readerPrefix+"/user-info", f.alice, nil, &info)
+	if info["userName"] != "alice" {
+		t.Errorf("user-info = %v", info)
+	}
+}
+
+func TestReaderSubscriptionsAndOrigin(t *testing.T) {
+	f := newAPIFixture(t)
+	mustRun(t, f.s, "login", "alice")
+	mustRun(t, f.s, "rename-follow", f.feedURL, "My blog")
+	mustRun(t, f.s, "folder", "add", "Work", f.feedURL)
+
+	var subs struct {
+		Subscriptions []readerSubscription `json:"subscriptions"`
+	}
+	f.readerGet(t, readerPrefix+"/subscription/list", f.alice, nil, &subs)
+	if len(subs.Subscriptions) != 1 {
+		t.Fatalf("subscriptions = %v", subs.Subscriptions)
+	}
+	sub := subs.Subscriptions[0]
+	if sub.Title != "My blog" || sub.URL != f.feedURL || len(sub.Categories) != 1 || sub.Categories[0].ID != labelStream("Work") {
+		t.Errorf("subscription = %+v", sub)
+	}
+
+	// Items name their feed the same way as the subscription list
+	for _, item := range f.readerItems(t, f.alice, nil).Items {
+		if item.Origin["title"] != sub.Title || item.Origin["streamId"] != sub.ID {
+			t.Errorf("origin of %q = %v, want the subscription's title and ID", item.Title, item.Origin)
+		}
+	}
+
+	if stream := f.readerItems(t, f.alice, url.Values{"s": {labelStream("Work")}}); len(stream.Items) != 5 {
+		t.Errorf("label stream: %v", readerTitles(stream))
+	}
+	if stream := f.readerItems(t, f.alice, url.Values{"s": {labelStream("Home")}}); len(stream.Items) != 0 {
+		t.Errorf("empty label stream: %v", readerTitles(stream))
+	}
+}
+
+func TestReaderStreamPagination(t *testing.T) {
+	f := newAPIFixture(t)
+
+	var titles []string
+	form := url.Values{"n": {"2"}}
+	for pages := 0; ; pages++ {
+		if pages > 5 {
+			t.Fatal("pagination doesn't end")
+		}
+		stream := f.readerItems(t, f.alice, form)
+		titles = append(titles, readerTitles(stream)...)
+		if stream.Continuation == "" {
+			break
+		}
+		form.Set("c", stream.Continuation)
+	}
+	if got := strings.Join(titles, ","); got != "Post 1,Post 2,Post 3,Post 4,Post 5" {
+		t.Errorf("pages = %s", got)
+	}
+
+	if stream := f.readerItems(t, f.alice, url.Values{"r": {"o"}, "n": {"1"}}); len(stream.Items) != 1 || stream.Items[0].Title != "Post 5" {
+		t.Errorf("oldest first: %v", readerTitles(stream))
+	}
+
+	for _, form := range []url.Values{{"s": {"feed/nope"}}, {"s": {"user/-/state/com.google/like"}}, {"c": {"-1"}}, {"ot": {"yesterday"}}} {
+		if code, body := f.readerRequest(t, http.MethodGet, readerPrefix+"/stream/contents", f.alice, form); code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400: %s", form.Encode(), code, body)
+		}
+	}
+}
+
+func TestReaderEditTag(t *testing.T) {
+	f := newAPIFixture(t)
+	items := f.readerItems(t, f.alice, nil).Items
+	first, second := items[0], items[1]
+
+	unread := func() []string {
+		t.Helper()
+		return readerTitles(f.readerItems(t, f.alice, url.Values{"xt": {readStream}}))
+	}
+	starred := func() []string {
+		t.Helper()
+		return readerTitles(f.readerItems(t, f.alice, url.Values{"s": {starredStream}}))
+	}
+
+	// Items can be named by their long form ID or by the short decimal one
+	n, err := parseReaderItemID(second.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.editTag(t, f.alice, url.Values{"a": {readStream}, "i": {first.ID, strconv.FormatInt(n, 10)}})
+	if got := unread(); strings.Join(got, ",") != "Post 3,Post 4,Post 5" {
+		t.Errorf("unread after reading two posts: %v", got)
+	}
+
+	var counts struct {
+		Max          int64               `json:"max"`
+		UnreadCounts []readerUnreadCount `json:"unreadcounts"`
+	}
+	f.readerGet(t, readerPrefix+"/unread-count", f.alice, nil, &counts)
+	if counts.Max != 3 {
+		t.Errorf("unread-count = %+v, want 3", counts)
+	}
+
+	f.editTag(t, f.alice, url.Values{"r": {readStream}, "a": {starredStream}, "i": {first.ID}})
+	if got := unread(); len(got) != 4 {
+		t.Errorf("unread after unreading one: %v", got)
+	}
+	if got := starred(); strings.Join(got, ",") != first.Title {
+		t.Errorf("starred = %v, want %s", got, first.Title)
+	}
+
+	// kept-unread clears the read state too; user IDs in tags are ignored
+	f.editTag(t, f.alice, url.Values{"a": {"user/1234/state/com.google/kept-unread"}, "r": {starredStream}, "i": {second.ID}})
+	if got := unread(); len(got) != 5 {
+		t.Errorf("unread after keeping one unread: %v", got)
+	}
+	f.editTag(t, f.alice, url.Values{"r": {"user/-/state/com.google/starred"}, "i": {first.ID}})
+	if got := starred(); len(got) != 0 {
+		t.Errorf("starred after unstarring: %v", got)
+	}
+
+	// Missing and malformed items are skipped
+	f.editTag(t, f.alice, url.Values{"a": {readStream}, "i": {readerItemPrefix + "xyz", "-1", readerItemPrefix + "0000000000000000"}})
+	if got := unread(); len(got) != 5 {
+		t.Errorf("unread after tagging missing items: %v", got)
+	}
+}
+
+func TestReaderItemsAreIsolated(t *testing.T) {
+	f := newAPIFixture(t)
+	item := f.readerItems(t, f.alice, nil).Items[0]
+
+	mustRun(t, f.s, "register", "bob")
+	bob := createToken(t, f.s, "bob")
+
+	// bob doesn't follow the feed, so he can neither read nor tag its posts
+	var stream readerStream
+	f.readerGet(t, readerPrefix+"/stream/items/contents", bob, url.Values{"i": {item.ID}}, &stream)
+	if len(stream.Items) != 0 {
+		t.Errorf("bob fetched %v", readerTitles(stream))
+	}
+	f.editTag(t, bob, url.Values{"a": {readStream}, "i": {item.ID}})
+	if got := f.readerItems(t, f.alice, url.Values{"xt": {readStream}}); len(got.Items) != 5 {
+		t.Errorf("bob's edit-tag changed alice's posts: %v", readerTitles(got))
+	}
+}
+
+func TestReaderLookupItemRange(t *testing.T) {
+	f := newAPIFixture(t)
+	ctx := context.Background()
+	feed, err := f.s.DB.GetFeedByUrl(ctx, f.feedURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	createPost := func(id, title string) {
+		t.Helper()
+		link := "https://example.com/" + id
+		_, err := f.s.DB.CreatePost(ctx, database.CreatePostParams{
+			ID:           uuid.MustParse(id),
+			CreatedAt:    time.Now().UTC(),
+			UpdatedAt:    time.Now().UTC(),
+			Title:        title,
+			Url:          link,
+			PublishedAt:  time.Now().UTC(),
+			FeedID:       feed.ID,
+			CanonicalUrl: urlnorm.Canonical(link),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Reader ID 1 covers UUIDs from 0000000000000002 to 0000000000000003ff..ff
+	createPost("00000000-0000-0001-ffff-ffffffffffff", "Below")
+	createPost("00000000-0000-0003-ffff-ffffffffffff", "Top of range")
+	createPost("00000000-0000-0004-0000-000000000000", "Above")
+
+	lookup := func(i string) []string {
+		t.Helper()
+		var stream readerStream
+		f.readerGet(t, readerPrefix+"/stream/items/contents", f.alice, url.Values{"i": {i}}, &stream)
+		return readerTitles(stream)
+	}
+
+	for i, want := range map[string]string{
+		"0":                                   "Below",
+		"1":                                   "Top of range",
+		readerItemPrefix + "0000000000000001": "Top of range",
+		"2":                                   "Above",
+		readerItemPrefix + "0000000000000002": "Above",
+	} {
+		if got := lookup(i); len(got) != 1 || got[0] != want {
+			t.Errorf("item %s = %v, want %s", i, got, want)
+		}
+	}
+	if got := lookup("3"); len(got) != 0 {
+		t.Errorf("item 3 = %v, want none", got)
+	}
+
+	// The lowest UUID in range wins when two posts share a reader ID
+	createPost("00000000-0000-0002-0000-000000000000", "Bottom of range")
+	if got := lookup("1"); len(got) != 1 || got[0] != "Bottom of range" {
+		t.Errorf("item 1 = %v, want Bottom of range", got)
+	}
+}
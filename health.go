@@ -0,0 +1,246 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	// How many of the most recent fetch errors /status reports
+	maxStatusErrors = 10
+	// How long /readyz waits for the database to answer
+	readyPingTimeout = 2 * time.Second
+	// How long requests in progress get to finish when a server stops
+	serverShutdownTimeout = 5 * time.Second
+)
+
+// aggStatus tracks what agg is doing for the health endpoints. It is
+// updated by the fetch loop and read by HTTP handlers, so every access
+// holds mu.
+type aggStatus struct {
+	mu sync.Mutex
+
+	startedAt time.Time
+	interval  time.Duration
+
+	cycles        int
+	failedCycles  int
+	lastCycleAt   time.Time
+	lastSuccessAt time.Time
+
+	inFlight      string
+	inFlightSince time.Time
+
+	// lastErrors holds the most recent errors, newest first
+	lastErrors []aggError
+}
+
+type aggError struct {
+	At      time.Time `json:"at"`
+	FeedURL string    `json:"feed_url,omitempty"`
+	Error   string    `json:"error"`
+}
+
+func newAggStatus(interval time.Duration) *aggStatus {
+	return &aggStatus{startedAt: time.Now(), interval: interval}
+}
+
+// fetchStarted records that feedURL is being fetched.
+func (st *aggStatus) fetchStarted(feedURL string) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	st.inFlight = feedURL
+	st.inFlightSince = time.Now()
+}
+
+// cycleDone records the outcome of a fetch cycle, attributing a failure to
+// the feed that was being fetched.
+func (st *aggStatus) cycleDone(err error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	now := time.Now()
+	st.cycles++
+	st.lastCycleAt = now
+	if err == nil {
+		st.lastSuccessAt = now
+	} else {
+		st.failedCycles++
+		st.lastErrors = append([]aggError{{At: now, FeedURL: st.inFlight, Error: err.Error()}}, st.lastErrors...)
+		if len(st.lastErrors) > maxStatusErrors {
+			st.lastErrors = st.lastErrors[:maxStatusErrors]
+		}
+	}
+	st.inFlight = ""
+}
+
+// startHealthServer serves /healthz, /readyz, /status and /metrics on
+// addr. agg is ready when the database answers and a fetch cycle succeeded
+// within readyWithin.
+func startHealthServer(addr string, s *state, st *aggStatus, readyWithin time.Duration) (*http.Server, error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+	})
+	mux.HandleFunc("GET /readyz", func(w http.ResponseWriter, r *http.Request) {
+		handleReadyz(w, r, s, st, readyWithin)
+	})
+	mux.HandleFunc("GET /status", func(w http.ResponseWriter, r *http.Request) {
+		handleStatus(w, r, s, st)
+	})
+	mux.Handle("GET /metrics", metricsHandler(s, st))
+
+	srv := &http.Server{Handler: mux, ReadHeaderTimeout: 5 * time.Second}
+	go func() {
+		if err := srv.Serve(ln); err != nil && err != http.ErrServerClosed {
+			slog.Error("health server stopped", "err", err)
+		}
+	}()
+
+	slog.Info("serving health checks and metrics", "addr", ln.Addr().String())
+	return srv, nil
+}
+
+// stopServer stops srv, giving requests in progress a moment to finish.
+func stopServer(srv *http.Server) {
+	ctx, cancel := context.WithTimeout(context.Background(), serverShutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != nil {
+		slog.Error("failed to stop HTTP server", "err", err)
+	}
+}
+
+func handleReadyz(w http.ResponseWriter, r *http.Request, s *state, st *aggStatus, readyWithin time.Duration) {
+	checks := map[string]string{"database": "ok", "fetch_cycle": "ok"}
+	ready := true
+
+	if s.Conn != nil {
+		ctx, cancel := context.WithTimeout(r.Context(), readyPingTimeout)
+		defer cancel()
+		if err := s.Conn.PingContext(ctx); err != nil {
+			checks["database"] = err.Error()
+			ready = false
+		}
+	}
+
+	st.mu.Lock()
+	lastSuccessAt := st.lastSuccessAt
+	st.mu.Unlock()
+
+	switch since := time.Since(lastSuccessAt); {
+	case lastSuccessAt.IsZero():
+		checks["fetch_cycle"] = "no fetch cycle has succeeded yet"
+		ready = false
+	case since > readyWithin:
+		checks["fetch_cycle"] = fmt.Sprintf("last successful fetch cycle was %s ago, more than %s", since.Round(time.Second), readyWithin)
+		ready = false
+	}
+
+	status, code := "ready", http.StatusOK
+	if !ready {
+		status, code = "not ready", http.StatusServiceUnavailable
+	}
+	writeJSON(w, code, map[string]any{"status": status, "checks": checks})
+}
+
+type statusResponse struct {
+	StartedAt     time.Time   `json:"started_at"`
+	Interval      string      `json:"interval"`
+	Cycles        int         `json:"cycles"`
+	FailedCycles  int         `json:"failed_cycles"`
+	LastCycleAt   *time.Time  `json:"last_cycle_at"`
+	LastSuccessAt *time.Time  `json:"last_success_at"`
+	InFlight      *inFlight   `json:"in_flight"`
+	Feeds         feedsStatus `json:"feeds"`
+	LastErrors    []aggError  `json:"last_errors"`
+}
+
+type inFlight struct {
+	FeedURL string    `json:"feed_url"`
+	Since   time.Time `json:"since"`
+}
+
+type feedsStatus struct {
+	Total        int `json:"total"`
+	NeverFetched int `json:"never_fetched"`
+	// Due counts feeds that haven't been fetched within one full rotation
+	// through all feeds at the current interval
+	Due   int    `json:"due"`
+	Error string `json:"error,omitempty"`
+}
+
+func handleStatus(w http.ResponseWriter, r *http.Request, s *state, st *aggStatus) {
+	st.mu.Lock()
+	resp := statusResponse{
+		StartedAt:     st.startedAt,
+		Interval:      st.interval.String(),
+		Cycles:        st.cycles,
+		FailedCycles:  st.failedCycles,
+		LastCycleAt:   timeOrNil(st.lastCycleAt),
+		LastSuccessAt: timeOrNil(st.lastSuccessAt),
+		LastErrors:    append([]aggError{}, st.lastErrors...),
+	}
+	if st.inFlight != "" {
+		resp.InFlight = &inFlight{FeedURL: st.inFlight, Since: st.inFlightSince}
+	}
+	interval := st.interval
+	st.mu.Unlock()
+
+	feeds, err := feedQueue(r.Context(), s, interval)
+	if err != nil {
+		feeds.Error = err.Error()
+	}
+	resp.Feeds = feeds
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// feedQueue counts the feeds agg has yet to get to when fetching one every
+// interval.
+func feedQueue(ctx context.Context, s *state, interval time.Duration) (feedsStatus, error) {
+	var feeds feedsStatus
+
+	fetchTimes, err := s.DB.GetFeedFetchTimes(ctx)
+	if err != nil {
+		return feeds, err
+	}
+
+	feeds.Total = len(fetchTimes)
+	dueBefore := time.Now().Add(-interval * time.Duration(len(fetchTimes)))
+	for _, fetchedAt := range fetchTimes {
+		if !fetchedAt.Valid {
+			feeds.NeverFetched++
+		}
+		if !fetchedAt.Valid || fetchedAt.Time.Before(dueBefore) {
+			feeds.Due++
+		}
+	}
+	return feeds, nil
+}
+
+func timeOrNil(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		slog.Warn("failed to write response", "err", err)
+	}
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+const globalUsage = "gator [--config <path>] [--profile <name>] <command> [arguments]"
+
+func handlerHelp(c *commands) func(*state, command) error {
+	return func(s *state, cmd command) error {
+		if len(cmd.Arguments) == 0 {
+			printHelp(os.Stdout, c)
+			return nil
+		}
+
+		spec, err := c.lookup(cmd.Arguments[0])
+		if err != nil {
+			return fmt.Errorf("help: %w", err)
+		}
+		printCommandHelp(os.Stdout, spec)
+		return nil
+	}
+}
+
+// printHelp prints the global usage and a one-line summary of every command.
+func printHelp(w io.Writer, c *commands) {
+	fmt.Fprintf(w, "Usage: %s\n\nCommands:\n", globalUsage)
+	for _, name := range c.names() {
+		fmt.Fprintf(w, "  %-14s %s\n", name, c.Specs[name].Summary)
+	}
+	fmt.Fprintln(w, "\nGlobal flags:")
+	fmt.Fprintln(w, "  --config <path>   read and write this config file")
+	fmt.Fprintln(w, "  --profile <name>  use this config profile")
+	fmt.Fprintln(w, "\nRun `gator help <command>` for details on a command.")
+}
+
+func printCommandHelp(w io.Writer, spec commandSpec) {
+	fmt.Fprintf(w, "Usage: %s\n\n%s\n", spec.synopsis(), spec.Summary)
+	if spec.requiresLogin() {
+		fmt.Fprintln(w, "\nRequires a logged in user.")
+	}
+
+	if spec.Flags != nil {
+		fs := flag.NewFlagSet(spec.Name, flag.ContinueOnError)
+		spec.Flags(fs)
+		fs.SetOutput(w)
+		fmt.Fprintln(w, "\nFlags:")
+		fs.PrintDefaults()
+	}
+
+	if len(spec.Subcommands) > 0 {
+		fmt.Fprintln(w, "\nSubcommands:")
+		for _, sub := range spec.Subcommands {
+			fmt.Fprintf(w, "  %-36s %s\n", strings.TrimSpace(sub.Name+" "+sub.Usage), sub.Summary)
+		}
+	}
+}
+
+// synopsis returns the command's usage line.
+func (spec commandSpec) synopsis() string {
+	parts := []string{"gator", spec.Name}
+	if len(spec.Subcommands) > 0 {
+		parts = append(parts, "<subcommand>", "[arguments]")
+	} else {
+		if spec.Flags != nil {
+			parts = append(parts, "[flags]")
+		}
+		if spec.Usage != "" {
+			parts = append(parts, spec.Usage)
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// wantsHelp reports whether args ask for help with -h or --help before any
+// "--" separator.
+func wantsHelp(args []string) bool {
+	for _, arg := range args {
+		switch arg {
+		case "--":
+			return false
+		case "-h", "-help", "--help":
+			return true
+		}
+	}
+	return false
+}
+
+// didYouMean returns a hint naming the candidate closest to name, or an
+// empty string when none is close enough to be a likely typo.
+func didYouMean(name string, candidates []string) string {
+	best, bestDistance := "", 0
+	for _, candidate := range candidates {
+		distance := editDistance(name, candidate)
+		if name != "" && strings.HasPrefix(candidate, name) {
+			distance = 1
+		}
+		if best == "" || distance < bestDistance {
+			best, bestDistance = candidate, distance
+		}
+	}
+
+	// Short names are a few edits away from almost anything
+	if best == "" || bestDistance > 2 || bestDistance > len(name)/2 {
+		return ""
+	}
+	return fmt.Sprintf("; did you mean %q?", best)
+}
+
+// editDistance returns the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
@@ -2,60 +2,669 @@ package config
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
 )
 
 const configFileNmae = ".gatorconfig.json"
 
-type Config struct {
+// DefaultProfile is the profile used when none is selected, and the one
+// that single-profile config files are migrated into.
+const DefaultProfile = "default"
+
+// Environment variables that override values from the config file
+const (
+	EnvDBURL = "GATOR_DB_URL"
+	EnvUser = "GATOR_USER"
+)
+
+// Source describes where the effective value of a setting came from.
+type Source string
+
+const (
+	SourceDefault Source = "default"
+	SourceFile Source = "file"
+	SourceEnv Source = "env"
+	SourceFlag Source = "flag"
+)
+
+// Profile holds the settings that differ between databases gator is used
+// with, such as a personal and a team database.
+type Profile struct {
 	DBURL string `json:"db_url"`
 	CurrentUsername string `json:"current_user_name"`
 }
 
+// fileLayout is the JSON stored in the config file.
+type fileLayout struct {
+	CurrentProfile string `json:"current_profile,omitempty"`
+	Profiles map[string]Profile `json:"profiles,omitempty"`
+	// The active profile's settings are mirrored here so that versions of
+	// gator from before profiles keep working; they are also where those
+	// versions stored them
+	DBURL string `json:"db_url,omitempty"`
+	CurrentUsername string `json:"current_user_name,omitempty"`
+	// Default post retention for feeds without their own override; zero keeps posts forever
+	RetentionDays int `json:"retention_days,omitempty"`
+	RetentionMaxPosts int `json:"retention_max_posts,omitempty"`
+	// Diagnostics logging: debug, info, warn or error, and text or json
+	LogLevel string `json:"log_level,omitempty"`
+	LogFormat string `json:"log_format,omitempty"`
+}
+
+type Config struct {
+	// Settings of the active profile
+	DBURL string
+	CurrentUsername string
+	// Default post retention for feeds without their own override; zero keeps posts forever
+	RetentionDays int
+	RetentionMaxPosts int
+	// Diagnostics logging: debug, info, warn or error, and text or json;
+	// empty means info and text
+	LogLevel string
+	LogFormat string
+
+	// Profile is the name of the active profile
+	Profile string
+
+	// fileProfile is the profile the file selects, which a --profile flag
+	// overrides without changing
+	fileProfile string
+	profiles map[string]Profile
+	// path is the file the config was read from and is written back to
+	path string
+	// sources maps each setting's JSON key to where its value came from
+	sources map[string]Source
+	// fileValues keeps the file's own value for settings overridden by the
+	// environment, so that writing the config back doesn't persist them
+	fileValues map[string]string
+}
+
 func (cfg *Config) SetUser(username string) error {
+	err := write(cfg, false, func(stored *Config) error {
+		stored.CurrentUsername = username
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// An explicit login wins over GATOR_USER for the rest of this run
 	cfg.CurrentUsername = username
-	return write(*cfg)
+	delete(cfg.fileValues, "current_user_name")
+	cfg.setSource("current_user_name", SourceFile)
+	return nil
 }
 
-func Read() (Config, error) {
-	filePath, err := getConfigFilePath()
+// SetDBURL sets db_url and writes the config file.
+func (cfg *Config) SetDBURL(dbURL string) error {
+	err := write(cfg, false, func(stored *Config) error {
+		stored.DBURL = dbURL
+		return nil
+	})
 	if err != nil {
-		return Config{}, err
+		return err
 	}
 
-	file, err := os.ReadFile(filePath)
+	cfg.DBURL = dbURL
+	delete(cfg.fileValues, "db_url")
+	cfg.setSource("db_url", SourceFile)
+	return nil
+}
+
+// Reset replaces the config file with one holding only profile, as the
+// active profile. Other profiles, settings and unknown keys are dropped.
+func (cfg *Config) Reset(profile Profile) error {
+	name := cfg.Profile
+	if name == "" {
+		name = DefaultProfile
+	}
+
+	err := write(cfg, true, func(stored *Config) error {
+		stored.Profile = name
+		stored.fileProfile = name
+		stored.DBURL = profile.DBURL
+		stored.CurrentUsername = profile.CurrentUsername
+		return nil
+	})
 	if err != nil {
-		return Config{}, err
+		return err
+	}
+
+	cfg.DBURL = profile.DBURL
+	cfg.CurrentUsername = profile.CurrentUsername
+	cfg.fileValues = nil
+	cfg.sources = nil
+	cfg.setSource("profile", SourceFile)
+	cfg.setSource("db_url", SourceFile)
+	cfg.setSource("current_user_name", SourceFile)
+	return nil
+}
+
+// Path returns the config file this config was read from.
+func (cfg *Config) Path() string {
+	return cfg.path
+}
+
+// Source reports where the setting with the given JSON key came from. The
+// key "profile" reports where the active profile was chosen.
+func (cfg *Config) Source(key string) Source {
+	if source, ok := cfg.sources[key]; ok {
+		return source
+	}
+	return SourceDefault
+}
+
+func (cfg *Config) setSource(key string, source Source) {
+	if cfg.sources == nil {
+		cfg.sources = make(map[string]Source)
+	}
+	cfg.sources[key] = source
+}
+
+// Profiles returns the names of all profiles, sorted.
+func (cfg *Config) Profiles() []string {
+	profiles := cfg.withActive()
+	names := make([]string, 0, len(profiles))
+	for name := range profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// GetProfile returns the stored settings of the named profile. They don't
+// include environment overrides.
+func (cfg *Config) GetProfile(name string) (Profile, bool) {
+	profile, ok := cfg.profiles[name]
+	if name == cfg.Profile {
+		profile = cfg.activeProfile()
+		ok = ok || profile != (Profile{})
+	}
+	return profile, ok
+}
+
+// withActive returns the stored profiles with the active one updated. An
+// active profile that has never had any settings isn't added.
+func (cfg *Config) withActive() map[string]Profile {
+	profiles := make(map[string]Profile, len(cfg.profiles)+1)
+	for name, profile := range cfg.profiles {
+		profiles[name] = profile
 	}
+	if active, ok := cfg.GetProfile(cfg.Profile); ok {
+		profiles[cfg.Profile] = active
+	}
+	return profiles
+}
+
+// activeProfile returns the active profile as it should be stored.
+func (cfg *Config) activeProfile() Profile {
+	profile := Profile{DBURL: cfg.DBURL, CurrentUsername: cfg.CurrentUsername}
+	if value, ok := cfg.fileValues["db_url"]; ok {
+		profile.DBURL = value
+	}
+	if value, ok := cfg.fileValues["current_user_name"]; ok {
+		profile.CurrentUsername = value
+	}
+	return profile
+}
+
+// AddProfile stores a new profile and writes the config file.
+func (cfg *Config) AddProfile(name string, profile Profile) error {
+	if err := validateProfileName(name); err != nil {
+		return err
+	}
+
+	return write(cfg, false, func(stored *Config) error {
+		if _, ok := stored.GetProfile(name); ok {
+			return fmt.Errorf("profile %q already exists", name)
+		}
+		if stored.profiles == nil {
+			stored.profiles = make(map[string]Profile)
+		}
+		stored.profiles[name] = profile
+		return nil
+	})
+}
 
-	var cfg Config
-	err = json.Unmarshal(file, &cfg)
+// RemoveProfile deletes a profile and writes the config file. The profile
+// the file selects can't be removed.
+func (cfg *Config) RemoveProfile(name string) error {
+	return write(cfg, false, func(stored *Config) error {
+		if _, ok := stored.GetProfile(name); !ok {
+			return fmt.Errorf("profile %q doesn't exist", name)
+		}
+		if name == stored.fileProfile || name == stored.Profile {
+			return fmt.Errorf("profile %q is in use; switch to another profile first", name)
+		}
+		delete(stored.profiles, name)
+		return nil
+	})
+}
+
+// UseProfile makes the named profile the active one, for this run and
+// later ones, and writes the config file.
+func (cfg *Config) UseProfile(name string) error {
+	err := write(cfg, false, func(stored *Config) error {
+		if _, ok := stored.GetProfile(name); !ok {
+			return fmt.Errorf("profile %q doesn't exist", name)
+		}
+
+		stored.profiles = stored.withActive()
+		profile := stored.profiles[name]
+		stored.Profile = name
+		stored.fileProfile = name
+		stored.DBURL = profile.DBURL
+		stored.CurrentUsername = profile.CurrentUsername
+		return nil
+	})
 	if err != nil {
-		return Config{}, err
+		return err
+	}
+
+	cfg.setSource("profile", SourceFile)
+	cfg.setSource("db_url", SourceFile)
+	cfg.setSource("current_user_name", SourceFile)
+	// Environment overrides still apply to the new profile
+	cfg.applyEnv()
+	return nil
+}
+
+func validateProfileName(name string) error {
+	if name == "" || strings.ContainsFunc(name, func(r rune) bool { return r <= ' ' || r == '/' }) {
+		return fmt.Errorf("invalid profile name %q", name)
+	}
+	return nil
+}
+
+// Read loads the config file at path, or at the default location when path
+// is empty, selects a profile and applies environment overrides. Settings
+// take their value from, in increasing order of precedence:
+//
+//  1. built-in defaults
+//  2. the active profile in the config file
+//  3. the GATOR_DB_URL and GATOR_USER environment variables
+//
+// The active profile is the one named by profile if it isn't empty, then
+// the file's current_profile, then "default". Files written before
+// profiles existed are read as a single "default" profile and saved in the
+// new layout the next time they are written.
+//
+// The default location is $XDG_CONFIG_HOME/gator/config.json
+// (~/.config/gator/config.json when XDG_CONFIG_HOME is unset) if that file
+// exists, and ~/.gatorconfig.json otherwise. A missing file at the default
+// location reads as empty so that gator can be configured through the
+// environment alone; a missing file named by path is an error. When the
+// file can't be read or parsed the returned Config still carries its path,
+// so that it can be rewritten from scratch, and when the profile doesn't
+// exist it carries everything else that was read.
+func Read(path, profile string) (Config, error) {
+	explicit := path != ""
+	if !explicit {
+		var err error
+		path, err = getConfigFilePath()
+		if err != nil {
+			return Config{}, err
+		}
 	}
 
+	cfg := Config{path: path}
+
+	var layout fileLayout
+	file, err := os.ReadFile(path)
+	switch {
+	case err == nil:
+		err = json.Unmarshal(file, &layout)
+		if err != nil {
+			return cfg, fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+	case errors.Is(err, fs.ErrNotExist) && !explicit:
+	default:
+		return cfg, err
+	}
+
+	cfg.loadLayout(layout)
+
+	cfg.Profile = cfg.fileProfile
+	if cfg.fileProfile != DefaultProfile || len(layout.Profiles) > 0 {
+		cfg.setSource("profile", SourceFile)
+	}
+	if profile != "" {
+		cfg.Profile = profile
+		cfg.setSource("profile", SourceFlag)
+	}
+
+	active, ok := cfg.profiles[cfg.Profile]
+	if !ok && profile != "" {
+		return cfg, fmt.Errorf("profile %q doesn't exist", profile)
+	}
+	cfg.DBURL = active.DBURL
+	cfg.CurrentUsername = active.CurrentUsername
+	if cfg.DBURL != "" {
+		cfg.setSource("db_url", SourceFile)
+	}
+	if cfg.CurrentUsername != "" {
+		cfg.setSource("current_user_name", SourceFile)
+	}
+
+	cfg.applyEnv()
+
 	return cfg, nil
 }
 
+// loadLayout sets the settings stored in a config file, apart from the
+// active profile's, which depend on the profile selected.
+func (cfg *Config) loadLayout(layout fileLayout) {
+	cfg.RetentionDays = layout.RetentionDays
+	cfg.RetentionMaxPosts = layout.RetentionMaxPosts
+	if cfg.RetentionDays != 0 {
+		cfg.setSource("retention_days", SourceFile)
+	}
+	if cfg.RetentionMaxPosts != 0 {
+		cfg.setSource("retention_max_posts", SourceFile)
+	}
+	cfg.LogLevel = layout.LogLevel
+	cfg.LogFormat = layout.LogFormat
+	if cfg.LogLevel != "" {
+		cfg.setSource("log_level", SourceFile)
+	}
+	if cfg.LogFormat != "" {
+		cfg.setSource("log_format", SourceFile)
+	}
+
+	cfg.profiles = layout.Profiles
+	cfg.fileProfile = layout.CurrentProfile
+	if len(cfg.profiles) == 0 && (layout.DBURL != "" || layout.CurrentUsername != "") {
+		cfg.profiles = map[string]Profile{
+			DefaultProfile: {DBURL: layout.DBURL, CurrentUsername: layout.CurrentUsername},
+		}
+	}
+	if cfg.fileProfile == "" {
+		cfg.fileProfile = DefaultProfile
+	}
+}
+
+// refresh updates cfg with the settings just written from stored, keeping
+// environment overrides in place.
+func (cfg *Config) refresh(stored *Config) {
+	switched := stored.Profile != cfg.Profile
+	if switched {
+		cfg.Profile = stored.Profile
+		cfg.fileValues = nil
+	}
+
+	cfg.profiles = stored.withActive()
+	cfg.fileProfile = stored.fileProfile
+	cfg.RetentionDays = stored.RetentionDays
+	cfg.RetentionMaxPosts = stored.RetentionMaxPosts
+	cfg.LogLevel = stored.LogLevel
+	cfg.LogFormat = stored.LogFormat
+
+	cfg.setStored("db_url", &cfg.DBURL, stored.DBURL)
+	cfg.setStored("current_user_name", &cfg.CurrentUsername, stored.CurrentUsername)
+}
+
+// setStored updates the file's value of a setting, which is the effective
+// value unless the environment overrides it.
+func (cfg *Config) setStored(key string, field *string, value string) {
+	if _, ok := cfg.fileValues[key]; ok {
+		cfg.fileValues[key] = value
+		return
+	}
+	*field = value
+}
+
+func (cfg *Config) applyEnv() {
+	cfg.override("db_url", EnvDBURL, &cfg.DBURL)
+	cfg.override("current_user_name", EnvUser, &cfg.CurrentUsername)
+}
+
+func (cfg *Config) override(key, env string, field *string) {
+	value := os.Getenv(env)
+	if value == "" {
+		return
+	}
+
+	if cfg.fileValues == nil {
+		cfg.fileValues = make(map[string]string)
+	}
+	cfg.fileValues[key] = *field
+	*field = value
+	cfg.setSource(key, SourceEnv)
+}
+
 func getConfigFilePath() (string, error) {
 	home, err := os.UserHomeDir()
 	if err != nil {
 		return "", err
 	}
-	return filepath.Join(home, configFileNmae), nil
+	legacy := filepath.Join(home, configFileNmae)
+
+	xdgHome := os.Getenv("XDG_CONFIG_HOME")
+	xdgSet := xdgHome != ""
+	if !xdgSet {
+		xdgHome = filepath.Join(home, ".config")
+	}
+	xdg := filepath.Join(xdgHome, "gator", "config.json")
+
+	if _, err := os.Stat(xdg); err == nil {
+		return xdg, nil
+	}
+	// New installs only go to the XDG location when the user asked for it
+	// by setting XDG_CONFIG_HOME
+	if _, err := os.Stat(legacy); err == nil || !xdgSet {
+		return legacy, nil
+	}
+	return xdg, nil
 }
 
-func write(cfg Config) error {
-	filePath, err := getConfigFilePath()
+// write applies change to the settings stored in cfg's file, saves them
+// and refreshes cfg with the result. It holds a lock on path+".lock" so
+// that concurrent gator processes write one at a time, and change is
+// applied to the file as it is under that lock rather than to cfg, so
+// changes other processes made since cfg was read are kept. The file is
+// replaced by renaming a fully written temporary file over it, so a crash
+// never leaves a truncated config behind. Keys this version of gator
+// doesn't know about, at the top level and in profiles, are kept as they
+// are in the file, unless fresh is set, in which case change starts from
+// an empty file.
+func write(cfg *Config, fresh bool, change func(stored *Config) error) error {
+	filePath := cfg.path
+	if filePath == "" {
+		var err error
+		filePath, err = getConfigFilePath()
+		if err != nil {
+			return err
+		}
+	}
+
+	// Write through symlinks rather than replacing them
+	if resolved, err := filepath.EvalSymlinks(filePath); err == nil {
+		filePath = resolved
+	}
+
+	err := os.MkdirAll(filepath.Dir(filePath), 0700)
 	if err != nil {
 		return err
 	}
 
-	jsonData, err := json.MarshalIndent(cfg, "", " ")
+	lock, err := os.OpenFile(filePath+".lock", os.O_RDWR|os.O_CREATE, 0600)
 	if err != nil {
 		return err
 	}
-	
-	return os.WriteFile(filePath, jsonData, 0600)
-}
\ No newline at end of file
+	defer lock.Close()
+	if err := lockFile(lock); err != nil {
+		return fmt.Errorf("failed to lock %s: %w", filePath, err)
+	}
+
+	// Read the file again now that no one else is writing it, to pick up
+	// changes made by other gator processes and keys written by other
+	// versions of gator
+	existing := map[string]json.RawMessage{}
+	var layout fileLayout
+	mode := os.FileMode(0600)
+	file, err := os.ReadFile(filePath)
+	switch {
+	case err == nil:
+		// A file that doesn't parse is overwritten rather than merged
+		if json.Unmarshal(file, &existing) != nil || existing == nil {
+			existing = map[string]json.RawMessage{}
+		}
+		if json.Unmarshal(file, &layout) != nil {
+			layout = fileLayout{}
+		}
+		if info, err := os.Stat(filePath); err == nil {
+			mode = info.Mode().Perm()
+		}
+	case errors.Is(err, fs.ErrNotExist):
+	default:
+		return err
+	}
+	if fresh {
+		existing = map[string]json.RawMessage{}
+		layout = fileLayout{}
+	}
+
+	stored := Config{path: cfg.path}
+	stored.loadLayout(layout)
+	stored.Profile = cfg.Profile
+	if stored.Profile == "" {
+		stored.Profile = stored.fileProfile
+	}
+	active := stored.profiles[stored.Profile]
+	stored.DBURL = active.DBURL
+	stored.CurrentUsername = active.CurrentUsername
+
+	if err := change(&stored); err != nil {
+		return err
+	}
+
+	jsonData, err := marshalLayout(stored, existing)
+	if err != nil {
+		return err
+	}
+
+	if err := writeFileAtomic(filePath, jsonData, mode); err != nil {
+		return err
+	}
+	cfg.refresh(&stored)
+	return nil
+}
+
+// marshalLayout encodes cfg in the file layout on top of the keys in
+// existing.
+func marshalLayout(cfg Config, existing map[string]json.RawMessage) ([]byte, error) {
+	profiles := cfg.withActive()
+
+	fileProfile := cfg.fileProfile
+	if fileProfile == "" {
+		fileProfile = DefaultProfile
+	}
+
+	layout := fileLayout{
+		CurrentProfile: fileProfile,
+		DBURL: profiles[fileProfile].DBURL,
+		CurrentUsername: profiles[fileProfile].CurrentUsername,
+		RetentionDays: cfg.RetentionDays,
+		RetentionMaxPosts: cfg.RetentionMaxPosts,
+		LogLevel: cfg.LogLevel,
+		LogFormat: cfg.LogFormat,
+	}
+
+	top, err := mergeKeys(existing, layout)
+	if err != nil {
+		return nil, err
+	}
+
+	var existingProfiles map[string]map[string]json.RawMessage
+	// Profiles in an unexpected shape are replaced
+	_ = json.Unmarshal(existing["profiles"], &existingProfiles)
+
+	mergedProfiles := make(map[string]map[string]json.RawMessage, len(profiles))
+	for name, profile := range profiles {
+		mergedProfiles[name], err = mergeKeys(existingProfiles[name], profile)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	delete(top, "profiles")
+	if len(mergedProfiles) > 0 {
+		top["profiles"], err = json.Marshal(mergedProfiles)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	jsonData, err := json.MarshalIndent(top, "", " ")
+	if err != nil {
+		return nil, err
+	}
+	return append(jsonData, '\n'), nil
+}
+
+// mergeKeys returns the keys of existing with those belonging to the
+// struct v replaced by v's encoding. Keys of v left out by omitempty are
+// removed.
+func mergeKeys(existing map[string]json.RawMessage, v any) (map[string]json.RawMessage, error) {
+	merged := make(map[string]json.RawMessage, len(existing))
+	for key, value := range existing {
+		merged[key] = value
+	}
+
+	t := reflect.TypeOf(v)
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if name != "" && name != "-" {
+			delete(merged, name)
+		}
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var encoded map[string]json.RawMessage
+	if err := json.Unmarshal(data, &encoded); err != nil {
+		return nil, err
+	}
+	for key, value := range encoded {
+		merged[key] = value
+	}
+	return merged, nil
+}
+
+// writeFileAtomic writes data to a temporary file next to path and renames
+// it over path once it is safely on disk.
+func writeFileAtomic(path string, data []byte, mode os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	// Removing fails harmlessly once the rename has happened
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(mode); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,177 @@
+package config
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// newConfigFile writes contents to a config file in a temporary directory
+// and returns its path, with the environment overrides cleared.
+func newConfigFile(t *testing.T, contents string) string {
+	t.Helper()
+	t.Setenv(EnvDBURL, "")
+	t.Setenv(EnvUser, "")
+
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(contents), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func mustRead(t *testing.T, path string) Config {
+	t.Helper()
+	cfg, err := Read(path, "")
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	return cfg
+}
+
+func readKeys(t *testing.T, path string) map[string]json.RawMessage {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var keys map[string]json.RawMessage
+	if err := json.Unmarshal(data, &keys); err != nil {
+		t.Fatalf("config file doesn't parse: %v\n%s", err, data)
+	}
+	return keys
+}
+
+func TestConcurrentWritesKeepEachOthersChanges(t *testing.T) {
+	path := newConfigFile(t, `{"db_url": "postgres://a", "current_user_name": "alice"}`)
+
+	// Two long-lived processes, such as `gator shell` and `agg`, read the
+	// config before either writes it
+	a := mustRead(t, path)
+	b := mustRead(t, path)
+
+	if err := b.AddProfile("team", Profile{DBURL: "postgres://team"}); err != nil {
+		t.Fatalf("AddProfile: %v", err)
+	}
+	if err := a.SetUser("bob"); err != nil {
+		t.Fatalf("SetUser: %v", err)
+	}
+
+	got := mustRead(t, path)
+	if got.CurrentUsername != "bob" {
+		t.Errorf("current user = %q, want bob", got.CurrentUsername)
+	}
+	if want := []string{DefaultProfile, "team"}; !reflect.DeepEqual(got.Profiles(), want) {
+		t.Errorf("profiles = %v, want %v", got.Profiles(), want)
+	}
+	if team, _ := got.GetProfile("team"); team.DBURL != "postgres://team" {
+		t.Errorf("team db_url = %q, want postgres://team", team.DBURL)
+	}
+
+	// The writer also sees the other process's change from then on
+	if want := []string{DefaultProfile, "team"}; !reflect.DeepEqual(a.Profiles(), want) {
+		t.Errorf("profiles in memory = %v, want %v", a.Profiles(), want)
+	}
+}
+
+func TestConcurrentProfileChanges(t *testing.T) {
+	path := newConfigFile(t, `{"db_url": "postgres://a"}`)
+
+	a := mustRead(t, path)
+	b := mustRead(t, path)
+
+	if err := a.AddProfile("team", Profile{DBURL: "postgres://team"}); err != nil {
+		t.Fatalf("AddProfile: %v", err)
+	}
+	// b hasn't seen "team" yet, but the file has it
+	if err := b.AddProfile("team", Profile{DBURL: "postgres://other"}); err == nil {
+		t.Error("adding a profile another process added succeeded")
+	}
+	if err := b.UseProfile("team"); err != nil {
+		t.Fatalf("UseProfile: %v", err)
+	}
+	if b.DBURL != "postgres://team" {
+		t.Errorf("db_url after switching = %q, want postgres://team", b.DBURL)
+	}
+	if err := a.RemoveProfile("team"); err == nil {
+		t.Error("removing the profile the file selects succeeded")
+	}
+
+	got := mustRead(t, path)
+	if got.Profile != "team" || got.DBURL != "postgres://team" {
+		t.Errorf("active profile = %q with db_url %q, want team with postgres://team", got.Profile, got.DBURL)
+	}
+}
+
+func TestWriteKeepsUnknownKeys(t *testing.T) {
+	path := newConfigFile(t, `{
+		"db_url": "postgres://a",
+		"future_setting": 42,
+		"profiles": {"default": {"db_url": "postgres://a", "future_profile_setting": true}}
+	}`)
+
+	cfg := mustRead(t, path)
+	if err := cfg.SetUser("bob"); err != nil {
+		t.Fatalf("SetUser: %v", err)
+	}
+
+	keys := readKeys(t, path)
+	if string(keys["future_setting"]) != "42" {
+		t.Errorf("future_setting = %s, want 42", keys["future_setting"])
+	}
+	var profiles map[string]map[string]json.RawMessage
+	if err := json.Unmarshal(keys["profiles"], &profiles); err != nil {
+		t.Fatal(err)
+	}
+	if string(profiles[DefaultProfile]["future_profile_setting"]) != "true" {
+		t.Errorf("profile keys = %v, want future_profile_setting kept", profiles[DefaultProfile])
+	}
+}
+
+func TestSetUserKeepsEnvironmentOverride(t *testing.T) {
+	path := newConfigFile(t, `{"db_url": "postgres://file"}`)
+	t.Setenv(EnvDBURL, "postgres://env")
+
+	cfg := mustRead(t, path)
+	if err := cfg.SetUser("bob"); err != nil {
+		t.Fatalf("SetUser: %v", err)
+	}
+
+	if cfg.DBURL != "postgres://env" {
+		t.Errorf("db_url = %q, want the environment's", cfg.DBURL)
+	}
+	if string(readKeys(t, path)["db_url"]) != `"postgres://file"` {
+		t.Errorf("environment override was written to the file")
+	}
+}
+
+func TestReset(t *testing.T) {
+	path := newConfigFile(t, `{
+		"current_profile": "team",
+		"profiles": {"default": {"db_url": "postgres://a"}, "team": {"db_url": "postgres://team"}},
+		"retention_days": 30,
+		"future_setting": 42
+	}`)
+
+	cfg := mustRead(t, path)
+	if err := cfg.Reset(Profile{DBURL: "sqlite:/tmp/gator.db", CurrentUsername: "bob"}); err != nil {
+		t.Fatalf("Reset: %v", err)
+	}
+
+	keys := readKeys(t, path)
+	for _, key := range []string{"retention_days", "future_setting"} {
+		if _, ok := keys[key]; ok {
+			t.Errorf("%s was kept", key)
+		}
+	}
+
+	got := mustRead(t, path)
+	if want := []string{"team"}; !reflect.DeepEqual(got.Profiles(), want) {
+		t.Errorf("profiles = %v, want %v", got.Profiles(), want)
+	}
+	if got.DBURL != "sqlite:/tmp/gator.db" || got.CurrentUsername != "bob" {
+		t.Errorf("got db_url %q and user %q", got.DBURL, got.CurrentUsername)
+	}
+}
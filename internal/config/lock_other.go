@@ -0,0 +1,11 @@
+//go:build !(darwin || dragonfly || freebsd || linux || netbsd || openbsd)
+
+package config
+
+import "os"
+
+// lockFile is a no-op where flock isn't available. Writes are still atomic,
+// but concurrent writers may each overwrite the other's changes.
+func lockFile(f *os.File) error {
+	return nil
+}
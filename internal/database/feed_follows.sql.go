@@ -7,15 +7,49 @@ package database
 
 import (
 	"context"
+	"database/sql"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+const clearFolder = `-- name: ClearFolder :execrows
+UPDATE feed_follows
+SET folder = NULL, updated_at = NOW()
+WHERE user_id = $1
+    AND folder = $2::text
+    AND ($3::text IS NULL OR feed_id = (SELECT id FROM feeds WHERE url = $3))
+`
+
+type ClearFolderParams struct {
+	UserID uuid.UUID
+	Folder string
+	Url    sql.NullString
+}
+
+func (q *Queries) ClearFolder(ctx context.Context, arg ClearFolderParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, clearFolder, arg.UserID, arg.Folder, arg.Url)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const countFeedFollowers = `-- name: CountFeedFollowers :one
+SELECT COUNT(*) FROM feed_follows WHERE feed_id = $1
+`
+
+func (q *Queries) CountFeedFollowers(ctx context.Context, feedID uuid.UUID) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countFeedFollowers, feedID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const createFeedFollow = `-- name: CreateFeedFollow :one
 INSERT INTO feed_follows (id, created_at, updated_at, user_id, feed_id)
 VALUES ($1, $2, $3, $4, $5)
-RETURNING feed_follows.id, feed_follows.created_at, feed_follows.updated_at, feed_follows.user_id, feed_follows.feed_id,
+RETURNING feed_follows.id, feed_follows.created_at, feed_follows.updated_at, feed_follows.user_id, feed_follows.feed_id, feed_follows.folder, feed_follows.display_name,
     (SELECT name FROM feeds WHERE feeds.id = feed_follows.feed_id) AS feed_name,
     (SELECT name FROM users WHERE users.id = feed_follows.user_id) AS user_name
 `
@@ -29,13 +63,15 @@ type CreateFeedFollowParams struct {
 }
 
 type CreateFeedFollowRow struct {
-	ID        uuid.UUID
-	CreatedAt time.Time
-	UpdatedAt time.Time
-	UserID    uuid.UUID
-	FeedID    uuid.UUID
-	FeedName  string
-	UserName  string
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	UserID      uuid.UUID
+	FeedID      uuid.UUID
+	Folder      sql.NullString
+	DisplayName sql.NullString
+	FeedName    string
+	UserName    string
 }
 
 func (q *Queries) CreateFeedFollow(ctx context.Context, arg CreateFeedFollowParams) (CreateFeedFollowRow, error) {
@@ -53,6 +89,8 @@ func (q *Queries) CreateFeedFollow(ctx context.Context, arg CreateFeedFollowPara
 		&i.UpdatedAt,
 		&i.UserID,
 		&i.FeedID,
+		&i.Folder,
+		&i.DisplayName,
 		&i.FeedName,
 		&i.UserName,
 	)
@@ -75,7 +113,7 @@ func (q *Queries) DeleteFeedFollow(ctx context.Context, arg DeleteFeedFollowPara
 }
 
 const getFeedByUrl = `-- name: GetFeedByUrl :one
-SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at FROM feeds WHERE url = $1
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, retention_days, retention_max_posts FROM feeds WHERE url = $1
 `
 
 func (q *Queries) GetFeedByUrl(ctx context.Context, url string) (Feed, error) {
@@ -89,26 +127,32 @@ func (q *Queries) GetFeedByUrl(ctx context.Context, url string) (Feed, error) {
 		&i.Url,
 		&i.UserID,
 		&i.LastFetchedAt,
+		&i.RetentionDays,
+		&i.RetentionMaxPosts,
 	)
 	return i, err
 }
 
 const getFeedFollowsForUser = `-- name: GetFeedFollowsForUser :many
-SELECT feed_follows.id, feed_follows.created_at, feed_follows.updated_at, feed_follows.user_id, feed_follows.feed_id,
-    (SELECT name FROM feeds WHERE feeds.id = feed_follows.feed_id) as feed_name,
-    (SELECT name FROM users WHERE users.id = feed_follows.user_id) as user_name
+SELECT feed_follows.id, feed_follows.created_at, feed_follows.updated_at, feed_follows.user_id, feed_follows.feed_id, feed_follows.folder, feed_follows.display_name,
+    COALESCE(feed_follows.display_name, (SELECT name FROM feeds WHERE feeds.id = feed_follows.feed_id)) as feed_name,
+    (SELECT name FROM users WHERE users.id = feed_follows.user_id) as user_name,
+    (SELECT url FROM feeds WHERE feeds.id = feed_follows.feed_id) as feed_url
 FROM feed_follows
 WHERE feed_follows.user_id = $1
 `
 
 type GetFeedFollowsForUserRow struct {
-	ID        uuid.UUID
-	CreatedAt time.Time
-	UpdatedAt time.Time
-	UserID    uuid.UUID
-	FeedID    uuid.UUID
-	FeedName  string
-	UserName  string
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	UserID      uuid.UUID
+	FeedID      uuid.UUID
+	Folder      sql.NullString
+	DisplayName sql.NullString
+	FeedName    string
+	UserName    string
+	FeedUrl     string
 }
 
 func (q *Queries) GetFeedFollowsForUser(ctx context.Context, userID uuid.UUID) ([]GetFeedFollowsForUserRow, error) {
@@ -126,8 +170,11 @@ func (q *Queries) GetFeedFollowsForUser(ctx context.Context, userID uuid.UUID) (
 			&i.UpdatedAt,
 			&i.UserID,
 			&i.FeedID,
+			&i.Folder,
+			&i.DisplayName,
 			&i.FeedName,
 			&i.UserName,
+			&i.FeedUrl,
 		); err != nil {
 			return nil, err
 		}
@@ -141,3 +188,63 @@ func (q *Queries) GetFeedFollowsForUser(ctx context.Context, userID uuid.UUID) (
 	}
 	return items, nil
 }
+
+const renameFolder = `-- name: RenameFolder :execrows
+UPDATE feed_follows
+SET folder = $1::text, updated_at = NOW()
+WHERE user_id = $2 AND folder = $3::text
+`
+
+type RenameFolderParams struct {
+	NewFolder string
+	UserID    uuid.UUID
+	OldFolder string
+}
+
+func (q *Queries) RenameFolder(ctx context.Context, arg RenameFolderParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, renameFolder, arg.NewFolder, arg.UserID, arg.OldFolder)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const setFeedFollowDisplayName = `-- name: SetFeedFollowDisplayName :execrows
+UPDATE feed_follows
+SET display_name = $3, updated_at = NOW()
+WHERE user_id = $1 AND feed_id = (SELECT id FROM feeds WHERE url = $2)
+`
+
+type SetFeedFollowDisplayNameParams struct {
+	UserID      uuid.UUID
+	Url         string
+	DisplayName sql.NullString
+}
+
+func (q *Queries) SetFeedFollowDisplayName(ctx context.Context, arg SetFeedFollowDisplayNameParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, setFeedFollowDisplayName, arg.UserID, arg.Url, arg.DisplayName)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const setFeedFollowFolder = `-- name: SetFeedFollowFolder :execrows
+UPDATE feed_follows
+SET folder = $3, updated_at = NOW()
+WHERE user_id = $1 AND feed_id = (SELECT id FROM feeds WHERE url = $2)
+`
+
+type SetFeedFollowFolderParams struct {
+	UserID uuid.UUID
+	Url    string
+	Folder sql.NullString
+}
+
+func (q *Queries) SetFeedFollowFolder(ctx context.Context, arg SetFeedFollowFolderParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, setFeedFollowFolder, arg.UserID, arg.Url, arg.Folder)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
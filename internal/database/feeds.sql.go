@@ -7,6 +7,7 @@ package database
 
 import (
 	"context"
+	"database/sql"
 	"time"
 
 	"github.com/google/uuid"
@@ -15,7 +16,7 @@ import (
 const createFeed = `-- name: CreateFeed :one
 INSERT INTO feeds (id, created_at, updated_at, name, url, user_id)
 VALUES ($1, $2, $3, $4, $5, $6)
-RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at
+RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at, retention_days, retention_max_posts
 `
 
 type CreateFeedParams struct {
@@ -45,10 +46,39 @@ func (q *Queries) CreateFeed(ctx context.Context, arg CreateFeedParams) (Feed, e
 		&i.Url,
 		&i.UserID,
 		&i.LastFetchedAt,
+		&i.RetentionDays,
+		&i.RetentionMaxPosts,
 	)
 	return i, err
 }
 
+const getFeedFetchTimes = `-- name: GetFeedFetchTimes :many
+SELECT last_fetched_at FROM feeds
+`
+
+func (q *Queries) GetFeedFetchTimes(ctx context.Context) ([]sql.NullTime, error) {
+	rows, err := q.db.QueryContext(ctx, getFeedFetchTimes)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []sql.NullTime
+	for rows.Next() {
+		var last_fetched_at sql.NullTime
+		if err := rows.Scan(&last_fetched_at); err != nil {
+			return nil, err
+		}
+		items = append(items, last_fetched_at)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getFeedsWithUserNames = `-- name: GetFeedsWithUserNames :many
 SELECT
     feeds.id,
@@ -102,7 +132,7 @@ func (q *Queries) GetFeedsWithUserNames(ctx context.Context) ([]GetFeedsWithUser
 }
 
 const getNextFeedToFetch = `-- name: GetNextFeedToFetch :one
-SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, retention_days, retention_max_posts
 FROM feeds
 ORDER BY last_fetched_at ASC NULLS FIRST
 LIMIT 1
@@ -119,6 +149,8 @@ func (q *Queries) GetNextFeedToFetch(ctx context.Context) (Feed, error) {
 		&i.Url,
 		&i.UserID,
 		&i.LastFetchedAt,
+		&i.RetentionDays,
+		&i.RetentionMaxPosts,
 	)
 	return i, err
 }
@@ -133,3 +165,20 @@ func (q *Queries) MarkFeedFetched(ctx context.Context, id uuid.UUID) error {
 	_, err := q.db.ExecContext(ctx, markFeedFetched, id)
 	return err
 }
+
+const setFeedRetention = `-- name: SetFeedRetention :exec
+UPDATE feeds
+SET retention_days = $2, retention_max_posts = $3, updated_at = NOW()
+WHERE id = $1
+`
+
+type SetFeedRetentionParams struct {
+	ID                uuid.UUID
+	RetentionDays     sql.NullInt32
+	RetentionMaxPosts sql.NullInt32
+}
+
+func (q *Queries) SetFeedRetention(ctx context.Context, arg SetFeedRetentionParams) error {
+	_, err := q.db.ExecContext(ctx, setFeedRetention, arg.ID, arg.RetentionDays, arg.RetentionMaxPosts)
+	return err
+}
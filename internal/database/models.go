@@ -11,33 +11,70 @@ import (
 	"github.com/google/uuid"
 )
 
-type Feed struct {
-	ID            uuid.UUID
-	CreatedAt     time.Time
-	UpdatedAt     time.Time
-	Name          string
-	Url           string
-	UserID        uuid.UUID
-	LastFetchedAt sql.NullTime
-}
-
-type FeedFollow struct {
+type ApiToken struct {
 	ID        uuid.UUID
 	CreatedAt time.Time
 	UpdatedAt time.Time
 	UserID    uuid.UUID
-	FeedID    uuid.UUID
+	Name      string
+	TokenHash string
 }
 
-type Post struct {
+type Feed struct {
+	ID                uuid.UUID
+	CreatedAt         time.Time
+	UpdatedAt         time.Time
+	Name              string
+	Url               string
+	UserID            uuid.UUID
+	LastFetchedAt     sql.NullTime
+	RetentionDays     sql.NullInt32
+	RetentionMaxPosts sql.NullInt32
+}
+
+type FeedFollow struct {
 	ID          uuid.UUID
 	CreatedAt   time.Time
 	UpdatedAt   time.Time
-	Title       string
-	Url         string
-	Description sql.NullString
-	PublishedAt time.Time
+	UserID      uuid.UUID
 	FeedID      uuid.UUID
+	Folder      sql.NullString
+	DisplayName sql.NullString
+}
+
+type Post struct {
+	ID           uuid.UUID
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+	Title        string
+	Url          string
+	Description  sql.NullString
+	PublishedAt  time.Time
+	FeedID       uuid.UUID
+	Author       sql.NullString
+	CanonicalUrl string
+}
+
+type PostState struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	UserID    uuid.UUID
+	PostID    uuid.UUID
+	ReadAt    sql.NullTime
+	SavedAt   sql.NullTime
+}
+
+type Rule struct {
+	ID            uuid.UUID
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+	UserID        uuid.UUID
+	Action        string
+	Scope         string
+	Pattern       string
+	IsRegex       bool
+	ApplyAtIngest bool
 }
 
 type User struct {
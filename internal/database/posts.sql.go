@@ -11,23 +11,26 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
 const createPost = `-- name: CreatePost :one
-INSERT INTO posts (id, created_at, updated_at, title, url, description, published_at, feed_id)
-VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
-RETURNING id, created_at, updated_at, title, url, description, published_at, feed_id
+INSERT INTO posts (id, created_at, updated_at, title, url, description, published_at, feed_id, author, canonical_url)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+RETURNING id, created_at, updated_at, title, url, description, published_at, feed_id, author, canonical_url
 `
 
 type CreatePostParams struct {
-	ID          uuid.UUID
-	CreatedAt   time.Time
-	UpdatedAt   time.Time
-	Title       string
-	Url         string
-	Description sql.NullString
-	PublishedAt time.Time
-	FeedID      uuid.UUID
+	ID           uuid.UUID
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+	Title        string
+	Url          string
+	Description  sql.NullString
+	PublishedAt  time.Time
+	FeedID       uuid.UUID
+	Author       sql.NullString
+	CanonicalUrl string
 }
 
 func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) (Post, error) {
@@ -40,6 +43,8 @@ func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) (Post, e
 		arg.Description,
 		arg.PublishedAt,
 		arg.FeedID,
+		arg.Author,
+		arg.CanonicalUrl,
 	)
 	var i Post
 	err := row.Scan(
@@ -51,34 +56,213 @@ func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) (Post, e
 		&i.Description,
 		&i.PublishedAt,
 		&i.FeedID,
+		&i.Author,
+		&i.CanonicalUrl,
+	)
+	return i, err
+}
+
+const deletePosts = `-- name: DeletePosts :execrows
+DELETE FROM posts
+WHERE id = ANY($1::uuid[])
+    AND NOT EXISTS (
+        SELECT 1 FROM post_states
+        WHERE post_states.post_id = posts.id AND post_states.saved_at IS NOT NULL
+    )
+`
+
+func (q *Queries) DeletePosts(ctx context.Context, ids []uuid.UUID) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deletePosts, pq.Array(ids))
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const getFeedNamesForCanonicalURLs = `-- name: GetFeedNamesForCanonicalURLs :many
+SELECT DISTINCT posts.canonical_url, COALESCE(feed_follows.display_name, feeds.name) AS feed_name
+FROM posts
+JOIN feeds ON posts.feed_id = feeds.id
+JOIN feed_follows ON feeds.id = feed_follows.feed_id
+WHERE feed_follows.user_id = $1
+    AND ($2::text IS NULL OR feed_follows.folder = $2)
+    AND posts.canonical_url = ANY($3::text[])
+ORDER BY posts.canonical_url, feed_name
+`
+
+type GetFeedNamesForCanonicalURLsParams struct {
+	UserID        uuid.UUID
+	Folder        sql.NullString
+	CanonicalUrls []string
+}
+
+type GetFeedNamesForCanonicalURLsRow struct {
+	CanonicalUrl string
+	FeedName     string
+}
+
+func (q *Queries) GetFeedNamesForCanonicalURLs(ctx context.Context, arg GetFeedNamesForCanonicalURLsParams) ([]GetFeedNamesForCanonicalURLsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getFeedNamesForCanonicalURLs, arg.UserID, arg.Folder, pq.Array(arg.CanonicalUrls))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetFeedNamesForCanonicalURLsRow
+	for rows.Next() {
+		var i GetFeedNamesForCanonicalURLsRow
+		if err := rows.Scan(&i.CanonicalUrl, &i.FeedName); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getPostForUser = `-- name: GetPostForUser :one
+SELECT posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id, posts.author, posts.canonical_url,
+    COALESCE(feed_follows.display_name, feeds.name) AS feed_name,
+    post_states.read_at,
+    post_states.saved_at
+FROM posts
+JOIN feeds ON posts.feed_id = feeds.id
+JOIN feed_follows ON feeds.id = feed_follows.feed_id
+LEFT JOIN post_states ON post_states.post_id = posts.id AND post_states.user_id = feed_follows.user_id
+WHERE feed_follows.user_id = $1 AND posts.id = $2
+`
+
+type GetPostForUserParams struct {
+	UserID uuid.UUID
+	ID     uuid.UUID
+}
+
+type GetPostForUserRow struct {
+	ID           uuid.UUID
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+	Title        string
+	Url          string
+	Description  sql.NullString
+	PublishedAt  time.Time
+	FeedID       uuid.UUID
+	Author       sql.NullString
+	CanonicalUrl string
+	FeedName     string
+	ReadAt       sql.NullTime
+	SavedAt      sql.NullTime
+}
+
+func (q *Queries) GetPostForUser(ctx context.Context, arg GetPostForUserParams) (GetPostForUserRow, error) {
+	row := q.db.QueryRowContext(ctx, getPostForUser, arg.UserID, arg.ID)
+	var i GetPostForUserRow
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Title,
+		&i.Url,
+		&i.Description,
+		&i.PublishedAt,
+		&i.FeedID,
+		&i.Author,
+		&i.CanonicalUrl,
+		&i.FeedName,
+		&i.ReadAt,
+		&i.SavedAt,
 	)
 	return i, err
 }
 
+const getPostIDInRange = `-- name: GetPostIDInRange :one
+SELECT id FROM posts
+WHERE id BETWEEN $1 AND $2
+ORDER BY id
+LIMIT 1
+`
+
+type GetPostIDInRangeParams struct {
+	IDFrom uuid.UUID
+	IDTo   uuid.UUID
+}
+
+func (q *Queries) GetPostIDInRange(ctx context.Context, arg GetPostIDInRangeParams) (uuid.UUID, error) {
+	row := q.db.QueryRowContext(ctx, getPostIDInRange, arg.IDFrom, arg.IDTo)
+	var id uuid.UUID
+	err := row.Scan(&id)
+	return id, err
+}
+
 const getPostsForUser = `-- name: GetPostsForUser :many
-SELECT posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id
+SELECT posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id, posts.author, posts.canonical_url,
+    COALESCE(feed_follows.display_name, feeds.name) AS feed_name,
+    post_states.read_at,
+    post_states.saved_at
 FROM posts
 JOIN feeds ON posts.feed_id = feeds.id
 JOIN feed_follows ON feeds.id = feed_follows.feed_id
+LEFT JOIN post_states ON post_states.post_id = posts.id AND post_states.user_id = feed_follows.user_id
 WHERE feed_follows.user_id = $1
-ORDER BY posts.published_at DESC
-LIMIT $2
+    AND ($2::text IS NULL OR feed_follows.folder = $2)
+    AND ($3::uuid IS NULL OR posts.feed_id = $3)
+    AND (NOT $4::boolean OR post_states.read_at IS NULL)
+    AND (NOT $5::boolean OR post_states.saved_at IS NOT NULL)
+    AND ($6::timestamp IS NULL OR posts.published_at > $6)
+ORDER BY CASE WHEN $7::boolean THEN posts.published_at END ASC, posts.published_at DESC
+LIMIT $8 OFFSET $9
 `
 
 type GetPostsForUserParams struct {
-	UserID uuid.UUID
-	Limit  int32
+	UserID         uuid.UUID
+	Folder         sql.NullString
+	FeedID         uuid.NullUUID
+	UnreadOnly     bool
+	SavedOnly      bool
+	PublishedAfter sql.NullTime
+	OldestFirst    bool
+	Limit          int32
+	Offset         int32
+}
+
+type GetPostsForUserRow struct {
+	ID           uuid.UUID
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+	Title        string
+	Url          string
+	Description  sql.NullString
+	PublishedAt  time.Time
+	FeedID       uuid.UUID
+	Author       sql.NullString
+	CanonicalUrl string
+	FeedName     string
+	ReadAt       sql.NullTime
+	SavedAt      sql.NullTime
 }
 
-func (q *Queries) GetPostsForUser(ctx context.Context, arg GetPostsForUserParams) ([]Post, error) {
-	rows, err := q.db.QueryContext(ctx, getPostsForUser, arg.UserID, arg.Limit)
+func (q *Queries) GetPostsForUser(ctx context.Context, arg GetPostsForUserParams) ([]GetPostsForUserRow, error) {
+	rows, err := q.db.QueryContext(ctx, getPostsForUser,
+		arg.UserID,
+		arg.Folder,
+		arg.FeedID,
+		arg.UnreadOnly,
+		arg.SavedOnly,
+		arg.PublishedAfter,
+		arg.OldestFirst,
+		arg.Limit,
+		arg.Offset,
+	)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []Post
+	var items []GetPostsForUserRow
 	for rows.Next() {
-		var i Post
+		var i GetPostsForUserRow
 		if err := rows.Scan(
 			&i.ID,
 			&i.CreatedAt,
@@ -88,6 +272,11 @@ func (q *Queries) GetPostsForUser(ctx context.Context, arg GetPostsForUserParams
 			&i.Description,
 			&i.PublishedAt,
 			&i.FeedID,
+			&i.Author,
+			&i.CanonicalUrl,
+			&i.FeedName,
+			&i.ReadAt,
+			&i.SavedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -101,3 +290,123 @@ func (q *Queries) GetPostsForUser(ctx context.Context, arg GetPostsForUserParams
 	}
 	return items, nil
 }
+
+const getPrunablePosts = `-- name: GetPrunablePosts :many
+WITH ranked AS (
+    SELECT
+        posts.id,
+        posts.title,
+        posts.published_at,
+        posts.feed_id,
+        feeds.name AS feed_name,
+        ROW_NUMBER() OVER (PARTITION BY posts.feed_id ORDER BY posts.published_at DESC) AS position,
+        COALESCE(feeds.retention_days, $1::int) AS keep_days,
+        COALESCE(feeds.retention_max_posts, $2::int) AS keep_posts
+    FROM posts
+    JOIN feeds ON posts.feed_id = feeds.id
+)
+SELECT ranked.id, ranked.title, ranked.feed_name, ranked.published_at
+FROM ranked
+WHERE NOT EXISTS (
+        SELECT 1 FROM post_states
+        WHERE post_states.post_id = ranked.id AND post_states.saved_at IS NOT NULL
+    )
+    AND (
+        (ranked.keep_days > 0 AND ranked.published_at < $3::timestamp - make_interval(days => ranked.keep_days))
+        OR (
+            ranked.keep_posts > 0 AND ranked.position > ranked.keep_posts
+            AND (
+                ranked.keep_days = 0
+                OR NOT EXISTS (
+                    SELECT 1 FROM feed_follows
+                    WHERE feed_follows.feed_id = ranked.feed_id
+                        AND NOT EXISTS (
+                            SELECT 1 FROM post_states
+                            WHERE post_states.post_id = ranked.id
+                                AND post_states.user_id = feed_follows.user_id
+                                AND post_states.read_at IS NOT NULL
+                        )
+                )
+            )
+        )
+    )
+ORDER BY ranked.feed_name, ranked.published_at
+`
+
+type GetPrunablePostsParams struct {
+	DefaultDays     int32
+	DefaultMaxPosts int32
+	Now             time.Time
+}
+
+type GetPrunablePostsRow struct {
+	ID          uuid.UUID
+	Title       string
+	FeedName    string
+	PublishedAt time.Time
+}
+
+func (q *Queries) GetPrunablePosts(ctx context.Context, arg GetPrunablePostsParams) ([]GetPrunablePostsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getPrunablePosts, arg.DefaultDays, arg.DefaultMaxPosts, arg.Now)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetPrunablePostsRow
+	for rows.Next() {
+		var i GetPrunablePostsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Title,
+			&i.FeedName,
+			&i.PublishedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getUnreadCountsForUser = `-- name: GetUnreadCountsForUser :many
+SELECT posts.feed_id, COUNT(*) AS unread
+FROM posts
+JOIN feed_follows ON posts.feed_id = feed_follows.feed_id
+LEFT JOIN post_states ON post_states.post_id = posts.id AND post_states.user_id = feed_follows.user_id
+WHERE feed_follows.user_id = $1 AND post_states.read_at IS NULL
+GROUP BY posts.feed_id
+`
+
+type GetUnreadCountsForUserRow struct {
+	FeedID uuid.UUID
+	Unread int64
+}
+
+func (q *Queries) GetUnreadCountsForUser(ctx context.Context, userID uuid.UUID) ([]GetUnreadCountsForUserRow, error) {
+	rows, err := q.db.QueryContext(ctx, getUnreadCountsForUser, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetUnreadCountsForUserRow
+	for rows.Next() {
+		var i GetUnreadCountsForUserRow
+		if err := rows.Scan(&i.FeedID, &i.Unread); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
@@ -0,0 +1,868 @@
+// Package memstore is an in-memory implementation of database.Querier. It
+// mirrors the behaviour of the SQL queries closely enough to run gator's
+// handlers without a live Postgres, which makes it useful for tests and
+// throwaway sessions.
+package memstore
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/josequiceno2000/gator/internal/database"
+)
+
+type Store struct {
+	mu          sync.Mutex
+	users       map[uuid.UUID]database.User
+	feeds       map[uuid.UUID]database.Feed
+	feedFollows map[uuid.UUID]database.FeedFollow
+	posts       map[uuid.UUID]database.Post
+	rules       map[uuid.UUID]database.Rule
+	postStates  map[uuid.UUID]database.PostState
+	apiTokens   map[uuid.UUID]database.ApiToken
+}
+
+var _ database.Querier = (*Store)(nil)
+
+func New() *Store {
+	s := &Store{}
+	s.reset()
+	return s
+}
+
+func (s *Store) reset() {
+	s.users = make(map[uuid.UUID]database.User)
+	s.feeds = make(map[uuid.UUID]database.Feed)
+	s.feedFollows = make(map[uuid.UUID]database.FeedFollow)
+	s.posts = make(map[uuid.UUID]database.Post)
+	s.rules = make(map[uuid.UUID]database.Rule)
+	s.postStates = make(map[uuid.UUID]database.PostState)
+	s.apiTokens = make(map[uuid.UUID]database.ApiToken)
+}
+
+// The error messages match Postgres so that callers checking for them,
+// like scrapeFeeds does for duplicate posts, behave the same way.
+func uniqueViolation(constraint string) error {
+	return fmt.Errorf("duplicate key value violates unique constraint %q", constraint)
+}
+
+func foreignKeyViolation(constraint string) error {
+	return fmt.Errorf("insert or update violates foreign key constraint %q", constraint)
+}
+
+func now() time.Time {
+	return time.Now().UTC()
+}
+
+// sortedValues returns the values of m ordered by creation time, standing
+// in for the insertion order a table scan would usually return.
+func sortedValues[T any](m map[uuid.UUID]T, createdAt func(T) time.Time) []T {
+	values := make([]T, 0, len(m))
+	for _, v := range m {
+		values = append(values, v)
+	}
+	sort.SliceStable(values, func(i, j int) bool {
+		return createdAt(values[i]).Before(createdAt(values[j]))
+	})
+	return values
+}
+
+func (s *Store) feedByURL(url string) (database.Feed, bool) {
+	for _, feed := range s.feeds {
+		if feed.Url == url {
+			return feed, true
+		}
+	}
+	return database.Feed{}, false
+}
+
+func (s *Store) follow(userID, feedID uuid.UUID) (database.FeedFollow, bool) {
+	for _, ff := range s.feedFollows {
+		if ff.UserID == userID && ff.FeedID == feedID {
+			return ff, true
+		}
+	}
+	return database.FeedFollow{}, false
+}
+
+func (s *Store) postState(userID, postID uuid.UUID) (database.PostState, bool) {
+	for _, ps := range s.postStates {
+		if ps.UserID == userID && ps.PostID == postID {
+			return ps, true
+		}
+	}
+	return database.PostState{}, false
+}
+
+func (s *Store) savedByAnyone(postID uuid.UUID) bool {
+	for _, ps := range s.postStates {
+		if ps.PostID == postID && ps.SavedAt.Valid {
+			return true
+		}
+	}
+	return false
+}
+
+func followFeedName(ff database.FeedFollow, feed database.Feed) string {
+	if ff.DisplayName.Valid {
+		return ff.DisplayName.String
+	}
+	return feed.Name
+}
+
+func (s *Store) deletePost(id uuid.UUID) {
+	delete(s.posts, id)
+	for psID, ps := range s.postStates {
+		if ps.PostID == id {
+			delete(s.postStates, psID)
+		}
+	}
+}
+
+func (s *Store) ClearFolder(ctx context.Context, arg database.ClearFolderParams) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var n int64
+	for id, ff := range s.feedFollows {
+		if ff.UserID != arg.UserID || !ff.Folder.Valid || ff.Folder.String != arg.Folder {
+			continue
+		}
+		if arg.Url.Valid && s.feeds[ff.FeedID].Url != arg.Url.String {
+			continue
+		}
+		ff.Folder = sql.NullString{}
+		ff.UpdatedAt = now()
+		s.feedFollows[id] = ff
+		n++
+	}
+	return n, nil
+}
+
+func (s *Store) CountFeedFollowers(ctx context.Context, feedID uuid.UUID) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var n int64
+	for _, ff := range s.feedFollows {
+		if ff.FeedID == feedID {
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (s *Store) CreateAPIToken(ctx context.Context, arg database.CreateAPITokenParams) (database.ApiToken, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.users[arg.UserID]; !ok {
+		return database.ApiToken{}, foreignKeyViolation("api_tokens_user_id_fkey")
+	}
+	for _, token := range s.apiTokens {
+		if token.TokenHash == arg.TokenHash {
+			return database.ApiToken{}, uniqueViolation("api_tokens_token_hash_key")
+		}
+		if token.UserID == arg.UserID && token.Name == arg.Name {
+			return database.ApiToken{}, uniqueViolation("api_tokens_user_id_name_key")
+		}
+	}
+
+	token := database.ApiToken(arg)
+	s.apiTokens[token.ID] = token
+	return token, nil
+}
+
+func (s *Store) CreateFeed(ctx context.Context, arg database.CreateFeedParams) (database.Feed, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.feedByURL(arg.Url); ok {
+		return database.Feed{}, uniqueViolation("feeds_url_key")
+	}
+	if _, ok := s.users[arg.UserID]; !ok {
+		return database.Feed{}, foreignKeyViolation("feeds_user_id_fkey")
+	}
+
+	feed := database.Feed{
+		ID:        arg.ID,
+		CreatedAt: arg.CreatedAt,
+		UpdatedAt: arg.UpdatedAt,
+		Name:      arg.Name,
+		Url:       arg.Url,
+		UserID:    arg.UserID,
+	}
+	s.feeds[feed.ID] = feed
+	return feed, nil
+}
+
+func (s *Store) CreateFeedFollow(ctx context.Context, arg database.CreateFeedFollowParams) (database.CreateFeedFollowRow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.follow(arg.UserID, arg.FeedID); ok {
+		return database.CreateFeedFollowRow{}, uniqueViolation("feed_follows_user_id_feed_id_key")
+	}
+	user, ok := s.users[arg.UserID]
+	if !ok {
+		return database.CreateFeedFollowRow{}, foreignKeyViolation("feed_follows_user_id_fkey")
+	}
+	feed, ok := s.feeds[arg.FeedID]
+	if !ok {
+		return database.CreateFeedFollowRow{}, foreignKeyViolation("feed_follows_feed_id_fkey")
+	}
+
+	ff := database.FeedFollow{
+		ID:        arg.ID,
+		CreatedAt: arg.CreatedAt,
+		UpdatedAt: arg.UpdatedAt,
+		UserID:    arg.UserID,
+		FeedID:    arg.FeedID,
+	}
+	s.feedFollows[ff.ID] = ff
+
+	return database.CreateFeedFollowRow{
+		ID:        ff.ID,
+		CreatedAt: ff.CreatedAt,
+		UpdatedAt: ff.UpdatedAt,
+		UserID:    ff.UserID,
+		FeedID:    ff.FeedID,
+		FeedName:  feed.Name,
+		UserName:  user.Name,
+	}, nil
+}
+
+func (s *Store) CreatePost(ctx context.Context, arg database.CreatePostParams) (database.Post, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, post := range s.posts {
+		if post.FeedID == arg.FeedID && post.Url == arg.Url {
+			return database.Post{}, uniqueViolation("posts_feed_id_url_key")
+		}
+	}
+	if _, ok := s.feeds[arg.FeedID]; !ok {
+		return database.Post{}, foreignKeyViolation("posts_feed_id_fkey")
+	}
+
+	post := database.Post(arg)
+	s.posts[post.ID] = post
+	return post, nil
+}
+
+func (s *Store) CreateRule(ctx context.Context, arg database.CreateRuleParams) (database.Rule, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.users[arg.UserID]; !ok {
+		return database.Rule{}, foreignKeyViolation("rules_user_id_fkey")
+	}
+
+	rule := database.Rule(arg)
+	s.rules[rule.ID] = rule
+	return rule, nil
+}
+
+func (s *Store) CreateUser(ctx context.Context, arg database.CreateUserParams) (database.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, user := range s.users {
+		if user.Name == arg.Name {
+			return database.User{}, uniqueViolation("users_name_key")
+		}
+	}
+
+	user := database.User(arg)
+	s.users[user.ID] = user
+	return user, nil
+}
+
+func (s *Store) DeleteAPIToken(ctx context.Context, arg database.DeleteAPITokenParams) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, token := range s.apiTokens {
+		if token.UserID == arg.UserID && token.Name == arg.Name {
+			delete(s.apiTokens, id)
+			return 1, nil
+		}
+	}
+	return 0, nil
+}
+
+func (s *Store) DeleteAllUsers(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Every other table cascades from users, directly or through feeds
+	s.reset()
+	return nil
+}
+
+func (s *Store) DeleteFeedFollow(ctx context.Context, arg database.DeleteFeedFollowParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	feed, ok := s.feedByURL(arg.Url)
+	if !ok {
+		return nil
+	}
+	if ff, ok := s.follow(arg.UserID, feed.ID); ok {
+		delete(s.feedFollows, ff.ID)
+	}
+	return nil
+}
+
+func (s *Store) DeletePosts(ctx context.Context, ids []uuid.UUID) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var n int64
+	for _, id := range ids {
+		if _, ok := s.posts[id]; !ok || s.savedByAnyone(id) {
+			continue
+		}
+		s.deletePost(id)
+		n++
+	}
+	return n, nil
+}
+
+func (s *Store) DeleteRule(ctx context.Context, arg database.DeleteRuleParams) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rule, ok := s.rules[arg.ID]
+	if !ok || rule.UserID != arg.UserID {
+		return 0, nil
+	}
+	delete(s.rules, arg.ID)
+	return 1, nil
+}
+
+func (s *Store) GetAPITokensForUser(ctx context.Context, userID uuid.UUID) ([]database.ApiToken, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var items []database.ApiToken
+	for _, token := range s.apiTokens {
+		if token.UserID == userID {
+			items = append(items, token)
+		}
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].Name < items[j].Name })
+	return items, nil
+}
+
+func (s *Store) GetFeedByUrl(ctx context.Context, url string) (database.Feed, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	feed, ok := s.feedByURL(url)
+	if !ok {
+		return database.Feed{}, sql.ErrNoRows
+	}
+	return feed, nil
+}
+
+func (s *Store) GetFeedFetchTimes(ctx context.Context) ([]sql.NullTime, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var items []sql.NullTime
+	for _, feed := range s.feeds {
+		items = append(items, feed.LastFetchedAt)
+	}
+	return items, nil
+}
+
+func (s *Store) GetFeedFollowsForUser(ctx context.Context, userID uuid.UUID) ([]database.GetFeedFollowsForUserRow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var items []database.GetFeedFollowsForUserRow
+	for _, ff := range sortedValues(s.feedFollows, func(ff database.FeedFollow) time.Time { return ff.CreatedAt }) {
+		if ff.UserID != userID {
+			continue
+		}
+		items = append(items, database.GetFeedFollowsForUserRow{
+			ID:          ff.ID,
+			CreatedAt:   ff.CreatedAt,
+			UpdatedAt:   ff.UpdatedAt,
+			UserID:      ff.UserID,
+			FeedID:      ff.FeedID,
+			Folder:      ff.Folder,
+			DisplayName: ff.DisplayName,
+			FeedName:    followFeedName(ff, s.feeds[ff.FeedID]),
+			UserName:    s.users[ff.UserID].Name,
+			FeedUrl:     s.feeds[ff.FeedID].Url,
+		})
+	}
+	return items, nil
+}
+
+func (s *Store) GetFeedNamesForCanonicalURLs(ctx context.Context, arg database.GetFeedNamesForCanonicalURLsParams) ([]database.GetFeedNamesForCanonicalURLsRow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	seen := make(map[database.GetFeedNamesForCanonicalURLsRow]bool)
+	var items []database.GetFeedNamesForCanonicalURLsRow
+	for _, post := range s.posts {
+		if !slices.Contains(arg.CanonicalUrls, post.CanonicalUrl) {
+			continue
+		}
+		ff, ok := s.follow(arg.UserID, post.FeedID)
+		if !ok {
+			continue
+		}
+		if arg.Folder.Valid && (!ff.Folder.Valid || ff.Folder.String != arg.Folder.String) {
+			continue
+		}
+
+		row := database.GetFeedNamesForCanonicalURLsRow{
+			CanonicalUrl: post.CanonicalUrl,
+			FeedName:     followFeedName(ff, s.feeds[post.FeedID]),
+		}
+		if !seen[row] {
+			seen[row] = true
+			items = append(items, row)
+		}
+	}
+
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].CanonicalUrl != items[j].CanonicalUrl {
+			return items[i].CanonicalUrl < items[j].CanonicalUrl
+		}
+		return items[i].FeedName < items[j].FeedName
+	})
+	return items, nil
+}
+
+func (s *Store) GetFeedsWithUserNames(ctx context.Context) ([]database.GetFeedsWithUserNamesRow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var items []database.GetFeedsWithUserNamesRow
+	for _, feed := range sortedValues(s.feeds, func(f database.Feed) time.Time { return f.CreatedAt }) {
+		items = append(items, database.GetFeedsWithUserNamesRow{
+			ID:        feed.ID,
+			CreatedAt: feed.CreatedAt,
+			UpdatedAt: feed.UpdatedAt,
+			Name:      feed.Name,
+			Url:       feed.Url,
+			UserName:  s.users[feed.UserID].Name,
+		})
+	}
+	return items, nil
+}
+
+func (s *Store) GetIngestMuteRulesForFeed(ctx context.Context, feedID uuid.UUID) ([]database.GetIngestMuteRulesForFeedRow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var items []database.GetIngestMuteRulesForFeedRow
+	for _, rule := range sortedValues(s.rules, func(r database.Rule) time.Time { return r.CreatedAt }) {
+		if rule.Action != "mute" || !rule.ApplyAtIngest {
+			continue
+		}
+		ff, ok := s.follow(rule.UserID, feedID)
+		if !ok {
+			continue
+		}
+		items = append(items, database.GetIngestMuteRulesForFeedRow{
+			ID:            rule.ID,
+			CreatedAt:     rule.CreatedAt,
+			UpdatedAt:     rule.UpdatedAt,
+			UserID:        rule.UserID,
+			Action:        rule.Action,
+			Scope:         rule.Scope,
+			Pattern:       rule.Pattern,
+			IsRegex:       rule.IsRegex,
+			ApplyAtIngest: rule.ApplyAtIngest,
+			FeedName:      followFeedName(ff, s.feeds[feedID]),
+		})
+	}
+	return items, nil
+}
+
+func (s *Store) GetNextFeedToFetch(ctx context.Context) (database.Feed, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	feeds := sortedValues(s.feeds, func(f database.Feed) time.Time { return f.CreatedAt })
+	if len(feeds) == 0 {
+		return database.Feed{}, sql.ErrNoRows
+	}
+
+	// ORDER BY last_fetched_at ASC NULLS FIRST
+	sort.SliceStable(feeds, func(i, j int) bool {
+		a, b := feeds[i].LastFetchedAt, feeds[j].LastFetchedAt
+		if !a.Valid || !b.Valid {
+			return !a.Valid && b.Valid
+		}
+		return a.Time.Before(b.Time)
+	})
+	return feeds[0], nil
+}
+
+func (s *Store) postRow(post database.Post, ff database.FeedFollow) database.GetPostsForUserRow {
+	row := database.GetPostsForUserRow{
+		ID:           post.ID,
+		CreatedAt:    post.CreatedAt,
+		UpdatedAt:    post.UpdatedAt,
+		Title:        post.Title,
+		Url:          post.Url,
+		Description:  post.Description,
+		PublishedAt:  post.PublishedAt,
+		FeedID:       post.FeedID,
+		Author:       post.Author,
+		CanonicalUrl: post.CanonicalUrl,
+		FeedName:     followFeedName(ff, s.feeds[post.FeedID]),
+	}
+	if ps, ok := s.postState(ff.UserID, post.ID); ok {
+		row.ReadAt = ps.ReadAt
+		row.SavedAt = ps.SavedAt
+	}
+	return row
+}
+
+func (s *Store) GetPostForUser(ctx context.Context, arg database.GetPostForUserParams) (database.GetPostForUserRow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	post, ok := s.posts[arg.ID]
+	if !ok {
+		return database.GetPostForUserRow{}, sql.ErrNoRows
+	}
+	ff, ok := s.follow(arg.UserID, post.FeedID)
+	if !ok {
+		return database.GetPostForUserRow{}, sql.ErrNoRows
+	}
+	return database.GetPostForUserRow(s.postRow(post, ff)), nil
+}
+
+func (s *Store) GetPostIDInRange(ctx context.Context, arg database.GetPostIDInRangeParams) (uuid.UUID, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var found []uuid.UUID
+	for id := range s.posts {
+		if bytes.Compare(id[:], arg.IDFrom[:]) >= 0 && bytes.Compare(id[:], arg.IDTo[:]) <= 0 {
+			found = append(found, id)
+		}
+	}
+	if len(found) == 0 {
+		return uuid.Nil, sql.ErrNoRows
+	}
+	return slices.MinFunc(found, func(a, b uuid.UUID) int { return bytes.Compare(a[:], b[:]) }), nil
+}
+
+func (s *Store) GetPostsForUser(ctx context.Context, arg database.GetPostsForUserParams) ([]database.GetPostsForUserRow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var items []database.GetPostsForUserRow
+	for _, post := range sortedValues(s.posts, func(p database.Post) time.Time { return p.CreatedAt }) {
+		ff, ok := s.follow(arg.UserID, post.FeedID)
+		if !ok {
+			continue
+		}
+		if arg.Folder.Valid && (!ff.Folder.Valid || ff.Folder.String != arg.Folder.String) {
+			continue
+		}
+		if arg.FeedID.Valid && post.FeedID != arg.FeedID.UUID {
+			continue
+		}
+		if arg.PublishedAfter.Valid && !post.PublishedAt.After(arg.PublishedAfter.Time) {
+			continue
+		}
+		row := s.postRow(post, ff)
+		if (arg.UnreadOnly && row.ReadAt.Valid) || (arg.SavedOnly && !row.SavedAt.Valid) {
+			continue
+		}
+		items = append(items, row)
+	}
+
+	sort.SliceStable(items, func(i, j int) bool {
+		if arg.OldestFirst {
+			return items[i].PublishedAt.Before(items[j].PublishedAt)
+		}
+		return items[i].PublishedAt.After(items[j].PublishedAt)
+	})
+
+	offset := min(max(int(arg.Offset), 0), len(items))
+	end := min(offset+max(int(arg.Limit), 0), len(items))
+	return items[offset:end], nil
+}
+
+func (s *Store) GetPrunablePosts(ctx context.Context, arg database.GetPrunablePostsParams) ([]database.GetPrunablePostsRow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	byFeed := make(map[uuid.UUID][]database.Post)
+	for _, post := range s.posts {
+		byFeed[post.FeedID] = append(byFeed[post.FeedID], post)
+	}
+
+	var items []database.GetPrunablePostsRow
+	for feedID, posts := range byFeed {
+		feed := s.feeds[feedID]
+
+		keepDays := arg.DefaultDays
+		if feed.RetentionDays.Valid {
+			keepDays = feed.RetentionDays.Int32
+		}
+		keepPosts := arg.DefaultMaxPosts
+		if feed.RetentionMaxPosts.Valid {
+			keepPosts = feed.RetentionMaxPosts.Int32
+		}
+
+		sort.SliceStable(posts, func(i, j int) bool {
+			return posts[i].PublishedAt.After(posts[j].PublishedAt)
+		})
+
+		for i, post := range posts {
+			if s.savedByAnyone(post.ID) {
+				continue
+			}
+
+			expired := keepDays > 0 && post.PublishedAt.Before(arg.Now.AddDate(0, 0, -int(keepDays)))
+			overCount := keepPosts > 0 && int32(i+1) > keepPosts && (keepDays == 0 || s.readByAllFollowers(post))
+			if expired || overCount {
+				items = append(items, database.GetPrunablePostsRow{
+					ID:          post.ID,
+					Title:       post.Title,
+					FeedName:    feed.Name,
+					PublishedAt: post.PublishedAt,
+				})
+			}
+		}
+	}
+
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].FeedName != items[j].FeedName {
+			return items[i].FeedName < items[j].FeedName
+		}
+		return items[i].PublishedAt.Before(items[j].PublishedAt)
+	})
+	return items, nil
+}
+
+func (s *Store) readByAllFollowers(post database.Post) bool {
+	for _, ff := range s.feedFollows {
+		if ff.FeedID != post.FeedID {
+			continue
+		}
+		if ps, ok := s.postState(ff.UserID, post.ID); !ok || !ps.ReadAt.Valid {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *Store) GetRulesForUser(ctx context.Context, userID uuid.UUID) ([]database.Rule, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var items []database.Rule
+	for _, rule := range sortedValues(s.rules, func(r database.Rule) time.Time { return r.CreatedAt }) {
+		if rule.UserID == userID {
+			items = append(items, rule)
+		}
+	}
+	return items, nil
+}
+
+func (s *Store) GetUnreadCountsForUser(ctx context.Context, userID uuid.UUID) ([]database.GetUnreadCountsForUserRow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	counts := make(map[uuid.UUID]int64)
+	for _, post := range s.posts {
+		if _, ok := s.follow(userID, post.FeedID); !ok {
+			continue
+		}
+		if ps, ok := s.postState(userID, post.ID); ok && ps.ReadAt.Valid {
+			continue
+		}
+		counts[post.FeedID]++
+	}
+
+	var items []database.GetUnreadCountsForUserRow
+	for feedID, n := range counts {
+		items = append(items, database.GetUnreadCountsForUserRow{FeedID: feedID, Unread: n})
+	}
+	return items, nil
+}
+
+func (s *Store) GetUser(ctx context.Context, name string) (database.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, user := range s.users {
+		if user.Name == name {
+			return user, nil
+		}
+	}
+	return database.User{}, sql.ErrNoRows
+}
+
+func (s *Store) GetUserByAPIToken(ctx context.Context, tokenHash string) (database.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, token := range s.apiTokens {
+		if token.TokenHash == tokenHash {
+			return s.users[token.UserID], nil
+		}
+	}
+	return database.User{}, sql.ErrNoRows
+}
+
+func (s *Store) GetUsers(ctx context.Context) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var items []string
+	for _, user := range sortedValues(s.users, func(u database.User) time.Time { return u.CreatedAt }) {
+		items = append(items, user.Name)
+	}
+	return items, nil
+}
+
+func (s *Store) MarkFeedFetched(ctx context.Context, id uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if feed, ok := s.feeds[id]; ok {
+		feed.LastFetchedAt = sql.NullTime{Time: now(), Valid: true}
+		feed.UpdatedAt = now()
+		s.feeds[id] = feed
+	}
+	return nil
+}
+
+func (s *Store) RenameFolder(ctx context.Context, arg database.RenameFolderParams) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var n int64
+	for id, ff := range s.feedFollows {
+		if ff.UserID != arg.UserID || !ff.Folder.Valid || ff.Folder.String != arg.OldFolder {
+			continue
+		}
+		ff.Folder = sql.NullString{String: arg.NewFolder, Valid: true}
+		ff.UpdatedAt = now()
+		s.feedFollows[id] = ff
+		n++
+	}
+	return n, nil
+}
+
+// updateFollow applies fn to the user's follow of the feed at url and
+// returns the number of rows changed.
+func (s *Store) updateFollow(userID uuid.UUID, url string, fn func(*database.FeedFollow)) int64 {
+	feed, ok := s.feedByURL(url)
+	if !ok {
+		return 0
+	}
+	ff, ok := s.follow(userID, feed.ID)
+	if !ok {
+		return 0
+	}
+	fn(&ff)
+	ff.UpdatedAt = now()
+	s.feedFollows[ff.ID] = ff
+	return 1
+}
+
+func (s *Store) SetFeedFollowDisplayName(ctx context.Context, arg database.SetFeedFollowDisplayNameParams) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.updateFollow(arg.UserID, arg.Url, func(ff *database.FeedFollow) {
+		ff.DisplayName = arg.DisplayName
+	}), nil
+}
+
+func (s *Store) SetFeedFollowFolder(ctx context.Context, arg database.SetFeedFollowFolderParams) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.updateFollow(arg.UserID, arg.Url, func(ff *database.FeedFollow) {
+		ff.Folder = arg.Folder
+	}), nil
+}
+
+func (s *Store) SetFeedRetention(ctx context.Context, arg database.SetFeedRetentionParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if feed, ok := s.feeds[arg.ID]; ok {
+		feed.RetentionDays = arg.RetentionDays
+		feed.RetentionMaxPosts = arg.RetentionMaxPosts
+		feed.UpdatedAt = now()
+		s.feeds[arg.ID] = feed
+	}
+	return nil
+}
+
+// upsertPostState inserts a post_states row or updates the existing one for
+// the same user and post, like the ON CONFLICT clause in the SQL.
+func (s *Store) upsertPostState(ps database.PostState, update func(*database.PostState)) error {
+	if _, ok := s.users[ps.UserID]; !ok {
+		return foreignKeyViolation("post_states_user_id_fkey")
+	}
+	if _, ok := s.posts[ps.PostID]; !ok {
+		return foreignKeyViolation("post_states_post_id_fkey")
+	}
+
+	if existing, ok := s.postState(ps.UserID, ps.PostID); ok {
+		update(&existing)
+		existing.UpdatedAt = ps.UpdatedAt
+		s.postStates[existing.ID] = existing
+		return nil
+	}
+
+	update(&ps)
+	s.postStates[ps.ID] = ps
+	return nil
+}
+
+func (s *Store) SetPostRead(ctx context.Context, arg database.SetPostReadParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.upsertPostState(database.PostState{
+		ID:        arg.ID,
+		CreatedAt: arg.CreatedAt,
+		UpdatedAt: arg.UpdatedAt,
+		UserID:    arg.UserID,
+		PostID:    arg.PostID,
+	}, func(ps *database.PostState) {
+		ps.ReadAt = arg.ReadAt
+	})
+}
+
+func (s *Store) SetPostSaved(ctx context.Context, arg database.SetPostSavedParams) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.upsertPostState(database.PostState{
+		ID:        arg.ID,
+		CreatedAt: arg.CreatedAt,
+		UpdatedAt: arg.UpdatedAt,
+		UserID:    arg.UserID,
+		PostID:    arg.PostID,
+	}, func(ps *database.PostState) {
+		ps.SavedAt = arg.SavedAt
+	})
+}
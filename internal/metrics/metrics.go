@@ -0,0 +1,297 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ContentType is the media type of the Prometheus text exposition format.
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// DefaultBuckets are histogram bounds in seconds, suitable for timing
+// network requests.
+var DefaultBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30}
+
+// Registry holds metrics and writes them in the Prometheus text format.
+type Registry struct {
+	mu      sync.Mutex
+	metrics []metric
+}
+
+type metric interface {
+	write(w *bufio.Writer)
+}
+
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+func (r *Registry) add(m metric) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.metrics = append(r.metrics, m)
+}
+
+// WriteText writes every metric in the order they were created.
+func (r *Registry) WriteText(w io.Writer) error {
+	r.mu.Lock()
+	metrics := append([]metric{}, r.metrics...)
+	r.mu.Unlock()
+
+	bw := bufio.NewWriter(w)
+	for _, m := range metrics {
+		m.write(bw)
+	}
+	return bw.Flush()
+}
+
+// Handler serves the registry's metrics.
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", ContentType)
+		r.WriteText(w)
+	})
+}
+
+// desc is what every metric has in common: a name, help text and the
+// names of its labels. Series are told apart by their label values,
+// joined with a separator that can't appear in valid UTF-8.
+type desc struct {
+	name   string
+	help   string
+	labels []string
+}
+
+const labelSep = "\xff"
+
+func (d desc) key(values []string) string {
+	if len(values) != len(d.labels) {
+		panic(fmt.Sprintf("metrics: %s takes %d label value(s), got %d", d.name, len(d.labels), len(values)))
+	}
+	return strings.Join(values, labelSep)
+}
+
+func (d desc) writeHeader(w *bufio.Writer, typ string) {
+	fmt.Fprintf(w, "# HELP %s %s\n", d.name, escapeHelp(d.help))
+	fmt.Fprintf(w, "# TYPE %s %s\n", d.name, typ)
+}
+
+// writeLabels writes {a="x",b="y"} for the series identified by key, plus
+// any extra label pairs.
+func (d desc) writeLabels(w *bufio.Writer, key string, extra ...string) {
+	var values []string
+	if len(d.labels) > 0 {
+		values = strings.Split(key, labelSep)
+	}
+	names := d.labels
+	if len(extra) > 0 {
+		names = append(append([]string{}, d.labels...), extra[0])
+		values = append(values, extra[1])
+	}
+	if len(names) == 0 {
+		return
+	}
+
+	w.WriteByte('{')
+	for i, name := range names {
+		if i > 0 {
+			w.WriteByte(',')
+		}
+		fmt.Fprintf(w, `%s="%s"`, name, labelEscaper.Replace(values[i]))
+	}
+	w.WriteByte('}')
+}
+
+// sortedKeys returns the keys of series in a stable order so that
+// consecutive scrapes list series the same way.
+func sortedKeys[V any](series map[string]V) []string {
+	keys := make([]string, 0, len(series))
+	for key := range series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Counter is a value that only goes up, such as a number of requests.
+type Counter struct {
+	desc
+	mu     sync.Mutex
+	series map[string]float64
+}
+
+// NewCounter creates a counter partitioned by the given labels.
+func (r *Registry) NewCounter(name, help string, labels ...string) *Counter {
+	c := &Counter{desc: desc{name, help, labels}, series: make(map[string]float64)}
+	if len(labels) == 0 {
+		c.series[""] = 0
+	}
+	r.add(c)
+	return c
+}
+
+// Inc adds one to the series with the given label values.
+func (c *Counter) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Add adds v, which must not be negative, to the series with the given
+// label values.
+func (c *Counter) Add(v float64, labelValues ...string) {
+	if v < 0 {
+		panic(fmt.Sprintf("metrics: counter %s can't decrease", c.name))
+	}
+	key := c.key(labelValues)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.series[key] += v
+}
+
+func (c *Counter) write(w *bufio.Writer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.writeHeader(w, "counter")
+	for _, key := range sortedKeys(c.series) {
+		w.WriteString(c.name)
+		c.writeLabels(w, key)
+		fmt.Fprintf(w, " %s\n", formatFloat(c.series[key]))
+	}
+}
+
+// Gauge is a value that can go up and down, such as a queue length.
+type Gauge struct {
+	desc
+	mu    sync.Mutex
+	value float64
+}
+
+func (r *Registry) NewGauge(name, help string) *Gauge {
+	g := &Gauge{desc: desc{name: name, help: help}}
+	r.add(g)
+	return g
+}
+
+func (g *Gauge) Set(v float64) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.value = v
+}
+
+func (g *Gauge) write(w *bufio.Writer) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.writeHeader(w, "gauge")
+	fmt.Fprintf(w, "%s %s\n", g.name, formatFloat(g.value))
+}
+
+// Histogram counts observations, such as request durations, in buckets
+// with the given upper bounds.
+type Histogram struct {
+	desc
+	buckets []float64
+	mu      sync.Mutex
+	series  map[string]*histogramSeries
+}
+
+type histogramSeries struct {
+	// counts[i] is the number of observations in buckets[i], which isn't
+	// cumulative; the last entry counts those above every bound
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+// NewHistogram creates a histogram partitioned by the given labels.
+// buckets must be sorted in increasing order.
+func (r *Registry) NewHistogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	if !sort.Float64sAreSorted(buckets) {
+		panic(fmt.Sprintf("metrics: buckets of %s aren't sorted", name))
+	}
+	h := &Histogram{desc: desc{name, help, labels}, buckets: buckets, series: make(map[string]*histogramSeries)}
+	if len(labels) == 0 {
+		h.series[""] = h.newSeries()
+	}
+	r.add(h)
+	return h
+}
+
+func (h *Histogram) newSeries() *histogramSeries {
+	return &histogramSeries{counts: make([]uint64, len(h.buckets)+1)}
+}
+
+// Observe records v in the series with the given label values.
+func (h *Histogram) Observe(v float64, labelValues ...string) {
+	key := h.key(labelValues)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	s, ok := h.series[key]
+	if !ok {
+		s = h.newSeries()
+		h.series[key] = s
+	}
+	s.counts[sort.SearchFloat64s(h.buckets, v)]++
+	s.sum += v
+	s.count++
+}
+
+func (h *Histogram) write(w *bufio.Writer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.writeHeader(w, "histogram")
+	for _, key := range sortedKeys(h.series) {
+		s := h.series[key]
+
+		var cumulative uint64
+		for i, bound := range h.buckets {
+			cumulative += s.counts[i]
+			w.WriteString(h.name + "_bucket")
+			h.writeLabels(w, key, "le", formatFloat(bound))
+			fmt.Fprintf(w, " %d\n", cumulative)
+		}
+		w.WriteString(h.name + "_bucket")
+		h.writeLabels(w, key, "le", "+Inf")
+		fmt.Fprintf(w, " %d\n", s.count)
+
+		w.WriteString(h.name + "_sum")
+		h.writeLabels(w, key)
+		fmt.Fprintf(w, " %s\n", formatFloat(s.sum))
+		w.WriteString(h.name + "_count")
+		h.writeLabels(w, key)
+		fmt.Fprintf(w, " %d\n", s.count)
+	}
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// The text format only knows these escapes
+var (
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+)
+
+func escapeHelp(s string) string {
+	return helpEscaper.Replace(s)
+}
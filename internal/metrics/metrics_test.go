@@ -0,0 +1,206 @@
+package metrics
+
+import (
+	"io"
+	"math"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func writeText(t *testing.T, r *Registry) string {
+	t.Helper()
+	var b strings.Builder
+	if err := r.WriteText(&b); err != nil {
+		t.Fatalf("WriteText: %v", err)
+	}
+	return b.String()
+}
+
+func TestCounter(t *testing.T) {
+	r := NewRegistry()
+	plain := r.NewCounter("gator_plain_total", "A counter without labels.")
+	fetches := r.NewCounter("gator_fetches_total", "Feed fetches by result.", "result")
+
+	plain.Add(2.5)
+	fetches.Inc("ok")
+	fetches.Inc("ok")
+	fetches.Inc("error")
+
+	want := `# HELP gator_plain_total A counter without labels.
+# TYPE gator_plain_total counter
+gator_plain_total 2.5
+# HELP gator_fetches_total Feed fetches by result.
+# TYPE gator_fetches_total counter
+gator_fetches_total{result="error"} 1
+gator_fetches_total{result="ok"} 2
+`
+	if got := writeText(t, r); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestCounterWithoutObservations(t *testing.T) {
+	r := NewRegistry()
+	r.NewCounter("gator_unlabelled_total", "Starts at zero.")
+	r.NewCounter("gator_labelled_total", "Has no series yet.", "result")
+
+	want := `# HELP gator_unlabelled_total Starts at zero.
+# TYPE gator_unlabelled_total counter
+gator_unlabelled_total 0
+# HELP gator_labelled_total Has no series yet.
+# TYPE gator_labelled_total counter
+`
+	if got := writeText(t, r); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestPanics(t *testing.T) {
+	r := NewRegistry()
+	c := r.NewCounter("gator_total", "help", "a", "b")
+
+	for name, fn := range map[string]func(){
+		"negative add":              func() { c.Add(-1, "x", "y") },
+		"too few label values":      func() { c.Inc("x") },
+		"too many label values":     func() { c.Inc("x", "y", "z") },
+		"label value without label": func() { r.NewCounter("gator_c", "help").Inc("x") },
+		"missing histogram label":   func() { r.NewHistogram("gator_h", "help", DefaultBuckets, "a").Observe(1) },
+		"unsorted buckets":          func() { r.NewHistogram("gator_seconds", "help", []float64{1, 0.5}) },
+	} {
+		t.Run(name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Error("didn't panic")
+				}
+			}()
+			fn()
+		})
+	}
+}
+
+func TestGauge(t *testing.T) {
+	r := NewRegistry()
+	g := r.NewGauge("gator_queue_length", "Feeds waiting to be fetched.")
+	g.Set(3)
+	g.Set(-1.5)
+
+	want := `# HELP gator_queue_length Feeds waiting to be fetched.
+# TYPE gator_queue_length gauge
+gator_queue_length -1.5
+`
+	if got := writeText(t, r); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestHistogram(t *testing.T) {
+	r := NewRegistry()
+	h := r.NewHistogram("gator_fetch_seconds", "Time taken to fetch a feed.", []float64{0.1, 1, 10}, "feed")
+
+	// A value equal to a bound falls in that bound's bucket, as le means
+	// less than or equal
+	for _, v := range []float64{0.05, 0.1, 0.5, 2, 20} {
+		h.Observe(v, "b")
+	}
+	h.Observe(0.5, "a")
+
+	want := `# HELP gator_fetch_seconds Time taken to fetch a feed.
+# TYPE gator_fetch_seconds histogram
+gator_fetch_seconds_bucket{feed="a",le="0.1"} 0
+gator_fetch_seconds_bucket{feed="a",le="1"} 1
+gator_fetch_seconds_bucket{feed="a",le="10"} 1
+gator_fetch_seconds_bucket{feed="a",le="+Inf"} 1
+gator_fetch_seconds_sum{feed="a"} 0.5
+gator_fetch_seconds_count{feed="a"} 1
+gator_fetch_seconds_bucket{feed="b",le="0.1"} 2
+gator_fetch_seconds_bucket{feed="b",le="1"} 3
+gator_fetch_seconds_bucket{feed="b",le="10"} 4
+gator_fetch_seconds_bucket{feed="b",le="+Inf"} 5
+gator_fetch_seconds_sum{feed="b"} 22.65
+gator_fetch_seconds_count{feed="b"} 5
+`
+	if got := writeText(t, r); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestHistogramWithoutLabels(t *testing.T) {
+	r := NewRegistry()
+	h := r.NewHistogram("gator_seconds", "Unlabelled.", []float64{1})
+
+	want := `# HELP gator_seconds Unlabelled.
+# TYPE gator_seconds histogram
+gator_seconds_bucket{le="1"} 0
+gator_seconds_bucket{le="+Inf"} 0
+gator_seconds_sum 0
+gator_seconds_count 0
+`
+	if got := writeText(t, r); got != want {
+		t.Errorf("before observing, got:\n%s\nwant:\n%s", got, want)
+	}
+
+	h.Observe(3)
+	want = `# HELP gator_seconds Unlabelled.
+# TYPE gator_seconds histogram
+gator_seconds_bucket{le="1"} 0
+gator_seconds_bucket{le="+Inf"} 1
+gator_seconds_sum 3
+gator_seconds_count 1
+`
+	if got := writeText(t, r); got != want {
+		t.Errorf("after observing, got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestEscaping(t *testing.T) {
+	r := NewRegistry()
+	c := r.NewCounter("gator_escaped_total", "Help with a \\ backslash\nand a newline, \"quotes\" kept.", "feed")
+	c.Inc(`C:\feeds "best"` + "\nsecond line")
+
+	want := `# HELP gator_escaped_total Help with a \\ backslash\nand a newline, "quotes" kept.
+# TYPE gator_escaped_total counter
+gator_escaped_total{feed="C:\\feeds \"best\"\nsecond line"} 1
+`
+	if got := writeText(t, r); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestFormatFloat(t *testing.T) {
+	r := NewRegistry()
+	g := r.NewGauge("g", "h")
+	for _, tt := range []struct {
+		v    float64
+		want string
+	}{
+		{0, "0"},
+		{42, "42"},
+		{0.25, "0.25"},
+		{1e21, "1e+21"},
+		{math.Inf(1), "+Inf"},
+		{math.Inf(-1), "-Inf"},
+		{math.NaN(), "NaN"},
+	} {
+		g.Set(tt.v)
+		if got := writeText(t, r); !strings.HasSuffix(got, "\ng "+tt.want+"\n") {
+			t.Errorf("Set(%v) wrote %q, want value %s", tt.v, got, tt.want)
+		}
+	}
+}
+
+func TestHandler(t *testing.T) {
+	r := NewRegistry()
+	r.NewCounter("gator_total", "Things.").Inc()
+
+	rec := httptest.NewRecorder()
+	r.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+
+	if ct := rec.Header().Get("Content-Type"); ct != ContentType {
+		t.Errorf("Content-Type = %q, want %q", ct, ContentType)
+	}
+	body, _ := io.ReadAll(rec.Body)
+	if !strings.Contains(string(body), "\ngator_total 1\n") {
+		t.Errorf("body = %q", body)
+	}
+}
@@ -0,0 +1,269 @@
+package render
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// hardBreak marks a <br> in collected inline text so that it survives
+// whitespace collapsing.
+const hardBreak = "\x00"
+
+type listState struct {
+	ordered bool
+	count   int
+}
+
+type renderer struct {
+	width   int
+	out     strings.Builder
+	inline  strings.Builder
+	links   []string
+	prefix  []string
+	lists   []listState
+	bullet  string
+	depth   int
+	pre     int
+	pending bool
+}
+
+// Text converts an HTML fragment into plain text wrapped to width columns.
+// Links are numbered and listed as footnotes, list items get bullets or
+// numbers, and emphasis is marked with _underscores_ and *asterisks*. A
+// width of zero or less disables wrapping.
+func Text(src string, width int) string {
+	doc, err := html.Parse(strings.NewReader(src))
+	if err != nil {
+		// html.Parse only fails on reader errors, which a strings.Reader never returns
+		return src
+	}
+
+	r := &renderer{width: width}
+	r.walk(doc)
+	r.flush()
+
+	text := strings.TrimRight(r.out.String(), "\n")
+	if len(r.links) == 0 {
+		return text
+	}
+
+	var b strings.Builder
+	b.WriteString(text)
+	b.WriteString("\n")
+	for i, link := range r.links {
+		fmt.Fprintf(&b, "\n[%d] %s", i+1, link)
+	}
+	return strings.TrimLeft(b.String(), "\n")
+}
+
+func (r *renderer) walk(n *html.Node) {
+	switch n.Type {
+	case html.TextNode:
+		r.inline.WriteString(n.Data)
+		return
+	case html.ElementNode:
+	default:
+		r.walkChildren(n)
+		return
+	}
+
+	switch n.DataAtom {
+	case atom.Script, atom.Style, atom.Head, atom.Iframe, atom.Object, atom.Embed, atom.Noscript, atom.Template:
+		return
+	case atom.Br:
+		if r.pre > 0 {
+			r.inline.WriteString("\n")
+		} else {
+			r.inline.WriteString(hardBreak)
+		}
+	case atom.Hr:
+		r.block(func() { r.inline.WriteString("----") })
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6:
+		level := int(n.Data[1] - '0')
+		r.block(func() {
+			r.inline.WriteString(strings.Repeat("#", level) + " ")
+			r.walkChildren(n)
+		})
+	case atom.P, atom.Div, atom.Section, atom.Article, atom.Header, atom.Footer,
+		atom.Figure, atom.Figcaption, atom.Table, atom.Tr, atom.Dl, atom.Dt, atom.Dd:
+		r.block(func() { r.walkChildren(n) })
+	case atom.Blockquote:
+		r.block(func() {
+			r.prefix = append(r.prefix, "> ")
+			r.walkChildren(n)
+			r.flush()
+			r.prefix = r.prefix[:len(r.prefix)-1]
+		})
+	case atom.Pre:
+		r.block(func() {
+			r.pre++
+			r.prefix = append(r.prefix, "    ")
+			r.walkChildren(n)
+			r.flush()
+			r.prefix = r.prefix[:len(r.prefix)-1]
+			r.pre--
+		})
+	case atom.Ul, atom.Ol:
+		list := func() {
+			r.lists = append(r.lists, listState{ordered: n.DataAtom == atom.Ol})
+			r.walkChildren(n)
+			r.flush()
+			r.lists = r.lists[:len(r.lists)-1]
+		}
+		if len(r.lists) == 0 {
+			r.block(list)
+		} else {
+			r.flush()
+			list()
+		}
+	case atom.Li:
+		r.flush()
+		bullet := "• "
+		if len(r.lists) > 0 {
+			list := &r.lists[len(r.lists)-1]
+			list.count++
+			if list.ordered {
+				bullet = fmt.Sprintf("%d. ", list.count)
+			}
+		}
+		r.prefix = append(r.prefix, strings.Repeat(" ", utf8.RuneCountInString(bullet)))
+		r.bullet, r.depth = bullet, len(r.prefix)
+		r.walkChildren(n)
+		r.flush()
+		// An empty item never flushed its bullet; don't let it leak into
+		// whatever comes next
+		r.bullet = ""
+		r.prefix = r.prefix[:len(r.prefix)-1]
+	case atom.A:
+		r.walkChildren(n)
+		href := attr(n, "href")
+		if href != "" && !strings.HasPrefix(href, "#") && !strings.HasPrefix(href, "javascript:") {
+			r.links = append(r.links, href)
+			fmt.Fprintf(&r.inline, "[%d]", len(r.links))
+		}
+	case atom.Img:
+		if alt := strings.TrimSpace(attr(n, "alt")); alt != "" {
+			fmt.Fprintf(&r.inline, "[image: %s]", alt)
+		} else {
+			r.inline.WriteString("[image]")
+		}
+	case atom.Em, atom.I:
+		r.wrapInline("_", n)
+	case atom.Strong, atom.B:
+		r.wrapInline("*", n)
+	case atom.Code:
+		if r.pre > 0 {
+			r.walkChildren(n)
+		} else {
+			r.wrapInline("`", n)
+		}
+	default:
+		r.walkChildren(n)
+	}
+}
+
+func (r *renderer) walkChildren(n *html.Node) {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		r.walk(c)
+	}
+}
+
+func (r *renderer) wrapInline(marker string, n *html.Node) {
+	r.inline.WriteString(marker)
+	r.walkChildren(n)
+	r.inline.WriteString(marker)
+}
+
+// block renders fn as a paragraph separated from its neighbours by blank lines.
+func (r *renderer) block(fn func()) {
+	r.flush()
+	r.pending = true
+	fn()
+	r.flush()
+	r.pending = true
+}
+
+// flush writes the collected inline text to the output, wrapped and
+// prefixed for the current nesting.
+func (r *renderer) flush() {
+	text := r.inline.String()
+	r.inline.Reset()
+
+	var lines []string
+	if r.pre > 0 {
+		lines = strings.Split(strings.Trim(text, "\n"), "\n")
+	} else {
+		for _, line := range strings.Split(text, hardBreak) {
+			lines = append(lines, strings.Join(strings.Fields(line), " "))
+		}
+	}
+
+	if strings.TrimSpace(strings.Join(lines, "")) == "" {
+		return
+	}
+
+	if r.pending && r.out.Len() > 0 {
+		r.out.WriteString("\n")
+	}
+	r.pending = false
+
+	indent := strings.Join(r.prefix, "")
+	first := indent
+	if r.bullet != "" && r.depth <= len(r.prefix) {
+		// The bullet takes the place of the list item's own indent
+		first = strings.Join(r.prefix[:r.depth-1], "") + r.bullet + strings.Join(r.prefix[r.depth:], "")
+		r.bullet = ""
+	}
+
+	width := 0
+	if r.width > 0 {
+		width = max(r.width-utf8.RuneCountInString(indent), 20)
+	}
+
+	for _, line := range lines {
+		if r.pre > 0 {
+			r.out.WriteString(first + line + "\n")
+			first = indent
+			continue
+		}
+		for _, wrapped := range wrap(line, width) {
+			r.out.WriteString(first + wrapped + "\n")
+			first = indent
+		}
+	}
+}
+
+// wrap breaks line into lines of at most width runes at word boundaries.
+// Words longer than width are left on a line of their own.
+func wrap(line string, width int) []string {
+	if width <= 0 {
+		return []string{line}
+	}
+
+	var lines []string
+	var cur strings.Builder
+	for _, word := range strings.Fields(line) {
+		if cur.Len() > 0 && utf8.RuneCountInString(cur.String())+1+utf8.RuneCountInString(word) > width {
+			lines = append(lines, cur.String())
+			cur.Reset()
+		}
+		if cur.Len() > 0 {
+			cur.WriteString(" ")
+		}
+		cur.WriteString(word)
+	}
+	return append(lines, cur.String())
+}
+
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
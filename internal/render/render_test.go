@@ -0,0 +1,64 @@
+package render
+
+import "testing"
+
+func TestText(t *testing.T) {
+	tests := []struct {
+		name  string
+		src   string
+		width int
+		want  string
+	}{
+		{"plain text", `hello   world`, 0, "hello world"},
+		{"paragraphs", `<p>one</p><p>two</p>`, 0, "one\n\ntwo"},
+		{"line break", `<p>one<br>two</p>`, 0, "one\ntwo"},
+		{"heading", `<h2>Title</h2><p>body</p>`, 0, "## Title\n\nbody"},
+		{"rule", `<p>a</p><hr><p>b</p>`, 0, "a\n\n----\n\nb"},
+		{"emphasis", `<p><em>soft</em> and <strong>loud</strong> <code>x := 1</code></p>`, 0, "_soft_ and *loud* `x := 1`"},
+		{"image", `<p><img src="a.png" alt="A cat"> <img src="b.png"></p>`, 0, "[image: A cat] [image]"},
+		{"scripts dropped", `<p>ok</p><script>alert(1)</script><style>p{}</style>`, 0, "ok"},
+
+		// Links
+		{"links become footnotes", `<p>See <a href="https://a.example">this</a> and <a href="https://b.example">that</a></p>`, 0,
+			"See this[1] and that[2]\n\n[1] https://a.example\n[2] https://b.example"},
+		{"fragment and javascript links", `<a href="#top">top</a> <a href="javascript:x()">run</a>`, 0, "top run"},
+		{"only a link", `<a href="https://a.example"></a>`, 0, "[1]\n\n[1] https://a.example"},
+
+		// Lists
+		{"unordered list", `<ul><li>one</li><li>two</li></ul>`, 0, "• one\n• two"},
+		{"ordered list", `<ol><li>one</li><li>two</li></ol>`, 0, "1. one\n2. two"},
+		{"nested list", `<ul><li>one<ul><li>inner</li></ul></li><li>two</li></ul>`, 0, "• one\n  • inner\n• two"},
+		{"nested ordered list", `<ol><li>one<ol><li>a</li><li>b</li></ol></li></ol>`, 0, "1. one\n   1. a\n   2. b"},
+		{"list after paragraph", `<p>intro</p><ul><li>item</li></ul>`, 0, "intro\n\n• item"},
+		{"wrapped list item", `<ul><li>one two three four five six seven eight nine ten eleven twelve</li></ul>`, 30,
+			"• one two three four five six\n  seven eight nine ten eleven\n  twelve"},
+		{"empty item skipped", `<ul><li></li><li>two</li></ul>`, 0, "• two"},
+		{"empty item before blockquote", `<ul><li></li></ul><blockquote>quoted text</blockquote>`, 40, "> quoted text"},
+		{"empty item before paragraph", `<ol><li> </li></ol><p>after</p>`, 0, "after"},
+
+		// Quotes and preformatted text
+		{"blockquote", `<blockquote><p>one</p><p>two</p></blockquote>`, 0, "> one\n\n> two"},
+		{"nested blockquote", `<blockquote>outer<blockquote>inner</blockquote></blockquote>`, 0, "> outer\n\n> > inner"},
+		{"wrapped blockquote", `<blockquote>one two three four five six seven eight nine ten eleven</blockquote>`, 30,
+			"> one two three four five six\n> seven eight nine ten eleven"},
+		{"pre", "<pre><code>func main() {\n\treturn\n}</code></pre>", 0, "    func main() {\n    \treturn\n    }"},
+		{"pre isn't wrapped", "<pre>one two three four five six seven eight nine ten</pre>", 20, "    one two three four five six seven eight nine ten"},
+
+		// Wrapping
+		{"wrap", `<p>one two three four five six seven eight nine ten eleven twelve thirteen</p>`, 20,
+			"one two three four\nfive six seven eight\nnine ten eleven\ntwelve thirteen"},
+		{"long word", `<p>a supercalifragilisticexpialidocious b</p>`, 20, "a\nsupercalifragilisticexpialidocious\nb"},
+		{"no wrap", `<p>one two three four five six seven eight nine ten eleven twelve thirteen</p>`, 0,
+			"one two three four five six seven eight nine ten eleven twelve thirteen"},
+
+		{"empty", ``, 40, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Text(tt.src, tt.width); got != tt.want {
+				t.Errorf("Text(%q, %d)\n got %q\nwant %q", tt.src, tt.width, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,255 @@
+package sanitize
+
+import (
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// allowedAttrs lists the elements that are kept and the attributes each
+// one may carry. Anything not listed is unwrapped, keeping its text.
+var allowedAttrs = map[atom.Atom][]string{
+	atom.A:          {"href", "title"},
+	atom.Abbr:       {"title"},
+	atom.B:          nil,
+	atom.Blockquote: {"cite"},
+	atom.Br:         nil,
+	atom.Caption:    nil,
+	atom.Cite:       nil,
+	atom.Code:       nil,
+	atom.Dd:         nil,
+	atom.Del:        nil,
+	atom.Div:        nil,
+	atom.Dl:         nil,
+	atom.Dt:         nil,
+	atom.Em:         nil,
+	atom.Figcaption: nil,
+	atom.Figure:     nil,
+	atom.H1:         nil,
+	atom.H2:         nil,
+	atom.H3:         nil,
+	atom.H4:         nil,
+	atom.H5:         nil,
+	atom.H6:         nil,
+	atom.Hr:         nil,
+	atom.I:          nil,
+	atom.Img:        {"src", "alt", "title", "width", "height"},
+	atom.Ins:        nil,
+	atom.Kbd:        nil,
+	atom.Li:         nil,
+	atom.Mark:       nil,
+	atom.Ol:         {"start"},
+	atom.P:          nil,
+	atom.Pre:        nil,
+	atom.Q:          {"cite"},
+	atom.S:          nil,
+	atom.Small:      nil,
+	atom.Span:       nil,
+	atom.Strong:     nil,
+	atom.Sub:        nil,
+	atom.Sup:        nil,
+	atom.Table:      nil,
+	atom.Tbody:      nil,
+	atom.Td:         {"colspan", "rowspan"},
+	atom.Tfoot:      nil,
+	atom.Th:         {"colspan", "rowspan"},
+	atom.Thead:      nil,
+	atom.Time:       {"datetime"},
+	atom.Tr:         nil,
+	atom.U:          nil,
+	atom.Ul:         nil,
+}
+
+// droppedElements are removed together with everything inside them.
+var droppedElements = map[atom.Atom]bool{
+	atom.Applet:   true,
+	atom.Audio:    true,
+	atom.Base:     true,
+	atom.Button:   true,
+	atom.Embed:    true,
+	atom.Form:     true,
+	atom.Frame:    true,
+	atom.Frameset: true,
+	atom.Head:     true,
+	atom.Iframe:   true,
+	atom.Input:    true,
+	atom.Link:     true,
+	atom.Math:     true,
+	atom.Meta:     true,
+	atom.Noscript: true,
+	atom.Object:   true,
+	atom.Script:   true,
+	atom.Select:   true,
+	atom.Style:    true,
+	atom.Svg:      true,
+	atom.Template: true,
+	atom.Textarea: true,
+	atom.Title:    true,
+	atom.Video:    true,
+}
+
+var urlAttrs = map[string]bool{
+	"href": true,
+	"src":  true,
+	"cite": true,
+}
+
+var allowedSchemes = map[string]bool{
+	"http":   true,
+	"https":  true,
+	"mailto": true,
+}
+
+// HTML returns src reduced to an allowlist of formatting elements and
+// attributes. Scripts, styles, embedded frames, event handlers and
+// tracking pixels are removed, links are limited to http, https and mailto,
+// and relative URLs are resolved against base (usually the item's link).
+func HTML(src, base string) string {
+	container := &html.Node{Type: html.ElementNode, Data: "div", DataAtom: atom.Div}
+	nodes, err := html.ParseFragment(strings.NewReader(src), container)
+	if err != nil {
+		return ""
+	}
+
+	baseURL, err := url.Parse(base)
+	if err != nil || !baseURL.IsAbs() {
+		baseURL = nil
+	}
+
+	var b strings.Builder
+	for _, n := range nodes {
+		for _, clean := range sanitizeNode(n, baseURL) {
+			if err := html.Render(&b, clean); err != nil {
+				return ""
+			}
+		}
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// sanitizeNode returns the nodes that replace n in the output: n itself
+// with its attributes filtered, its sanitized children when n is unwrapped,
+// or nothing when n is dropped.
+func sanitizeNode(n *html.Node, base *url.URL) []*html.Node {
+	switch n.Type {
+	case html.TextNode:
+		return []*html.Node{{Type: html.TextNode, Data: n.Data}}
+	case html.ElementNode:
+	case html.DocumentNode:
+		return sanitizeChildren(n, base)
+	default:
+		// Comments, doctypes and raw nodes never reach the output
+		return nil
+	}
+
+	if droppedElements[n.DataAtom] {
+		return nil
+	}
+
+	allowed, ok := allowedAttrs[n.DataAtom]
+	if !ok || n.DataAtom == 0 {
+		return sanitizeChildren(n, base)
+	}
+
+	clean := &html.Node{Type: html.ElementNode, Data: n.Data, DataAtom: n.DataAtom}
+	for _, a := range n.Attr {
+		if a.Namespace != "" || !slices.Contains(allowed, a.Key) {
+			continue
+		}
+		if urlAttrs[a.Key] {
+			resolved, ok := safeURL(a.Val, base)
+			if !ok {
+				continue
+			}
+			a.Val = resolved
+		}
+		clean.Attr = append(clean.Attr, html.Attribute{Key: a.Key, Val: a.Val})
+	}
+
+	switch n.DataAtom {
+	case atom.Img:
+		if !hasAttr(clean, "src") || isTrackingPixel(clean) {
+			return nil
+		}
+	case atom.A:
+		if hasAttr(clean, "href") {
+			clean.Attr = append(clean.Attr, html.Attribute{Key: "rel", Val: "nofollow noopener noreferrer"})
+		}
+	}
+
+	for _, child := range sanitizeChildren(n, base) {
+		clean.AppendChild(child)
+	}
+	return []*html.Node{clean}
+}
+
+func sanitizeChildren(n *html.Node, base *url.URL) []*html.Node {
+	var out []*html.Node
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		out = append(out, sanitizeNode(c, base)...)
+	}
+	return out
+}
+
+// safeURL resolves raw against base and reports whether the result uses an
+// allowed scheme. Whitespace and control characters are stripped first
+// because browsers ignore them, which would otherwise let "java\tscript:"
+// slip past the scheme check.
+func safeURL(raw string, base *url.URL) (string, bool) {
+	raw = strings.Map(func(r rune) rune {
+		if r <= ' ' || r == 0x7f {
+			return -1
+		}
+		return r
+	}, raw)
+	if raw == "" {
+		return "", false
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", false
+	}
+	if base != nil {
+		u = base.ResolveReference(u)
+	}
+
+	if u.Scheme == "" {
+		// Still relative because there was no usable base
+		return u.String(), true
+	}
+	if !allowedSchemes[strings.ToLower(u.Scheme)] {
+		return "", false
+	}
+	return u.String(), true
+}
+
+// isTrackingPixel reports whether an image is declared at 1x1 or smaller,
+// the usual shape of a tracking beacon.
+func isTrackingPixel(n *html.Node) bool {
+	width, werr := strconv.Atoi(strings.TrimSuffix(attrValue(n, "width"), "px"))
+	height, herr := strconv.Atoi(strings.TrimSuffix(attrValue(n, "height"), "px"))
+	return werr == nil && herr == nil && width <= 1 && height <= 1
+}
+
+func hasAttr(n *html.Node, key string) bool {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+func attrValue(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
@@ -0,0 +1,146 @@
+package sanitize
+
+import (
+	"strings"
+	"testing"
+)
+
+const base = "https://example.com/blog/post.html"
+
+func TestHTML(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		// Scripts and other active content
+		{"script", `<p>hi</p><script>alert(1)</script>`, `<p>hi</p>`},
+		{"script with src", `<script src="https://evil.example/x.js"></script>text`, `text`},
+		{"uppercase script", `<SCRIPT>alert(1)</SCRIPT>ok`, `ok`},
+		{"script split by comment", `<scr<!-- -->ipt>alert(1)</script>`, `ipt&gt;alert(1)`},
+		{"noscript", `<noscript><img src="https://evil.example/x.gif"></noscript>ok`, `ok`},
+		{"svg with onload", `<svg onload="alert(1)"><circle r="1"/></svg>ok`, `ok`},
+		{"math", `<math><mi xlink:href="javascript:alert(1)">x</mi></math>ok`, `ok`},
+		{"template", `<template><script>alert(1)</script></template>ok`, `ok`},
+		{"style element", `<style>body{display:none}</style><p>ok</p>`, `<p>ok</p>`},
+		{"form", `<form action="https://evil.example"><input name="pw"><button>Go</button></form>ok`, `ok`},
+		{"meta refresh", `<meta http-equiv="refresh" content="0;url=https://evil.example">ok`, `ok`},
+		{"base href", `<base href="https://evil.example/"><a href="/x">x</a>`, `<a href="https://example.com/x" rel="nofollow noopener noreferrer">x</a>`},
+		{"comment", `<!-- <script>alert(1)</script> -->ok`, `ok`},
+
+		// Event handlers
+		{"onclick", `<p onclick="alert(1)">hi</p>`, `<p>hi</p>`},
+		{"onerror on img", `<img src="a.png" onerror="alert(1)">`, `<img src="https://example.com/blog/a.png"/>`},
+		{"onmouseover on link", `<a href="https://example.com" onmouseover="alert(1)">x</a>`, `<a href="https://example.com" rel="nofollow noopener noreferrer">x</a>`},
+		{"handler on unwrapped element", `<font onmouseover="alert(1)">text</font>`, `text`},
+
+		// Dangerous URLs
+		{"javascript href", `<a href="javascript:alert(1)">x</a>`, `<a>x</a>`},
+		{"uppercase javascript href", `<a href="JaVaScRiPt:alert(1)">x</a>`, `<a>x</a>`},
+		{"javascript href with tab", "<a href=\"java\tscript:alert(1)\">x</a>", `<a>x</a>`},
+		{"javascript href with entity", `<a href="jav&#x61;script:alert(1)">x</a>`, `<a>x</a>`},
+		{"javascript href with leading space", `<a href="  javascript:alert(1)">x</a>`, `<a>x</a>`},
+		{"data href", `<a href="data:text/html;base64,PHNjcmlwdD5hbGVydCgxKTwvc2NyaXB0Pg==">x</a>`, `<a>x</a>`},
+		{"data img", `<img src="data:image/svg+xml,<svg onload=alert(1)>">`, ``},
+		{"vbscript href", `<a href="vbscript:msgbox(1)">x</a>`, `<a>x</a>`},
+		{"javascript cite", `<blockquote cite="javascript:alert(1)">q</blockquote>`, `<blockquote>q</blockquote>`},
+		{"mailto kept", `<a href="mailto:me@example.com">mail</a>`, `<a href="mailto:me@example.com" rel="nofollow noopener noreferrer">mail</a>`},
+
+		// Embedded content
+		{"iframe", `<iframe src="https://evil.example"></iframe><p>ok</p>`, `<p>ok</p>`},
+		{"iframe with content", `<iframe src="https://evil.example">fallback <b>text</b></iframe>ok`, `ok`},
+		{"object", `<object data="https://evil.example/x.swf"><param name="a" value="b"></object>ok`, `ok`},
+		{"embed", `<embed src="https://evil.example/x.swf">ok`, `ok`},
+		{"video", `<video src="https://example.com/v.mp4" autoplay></video>ok`, `ok`},
+
+		// Styles and unknown attributes
+		{"style attribute", `<p style="position:fixed;top:0">hi</p>`, `<p>hi</p>`},
+		{"class and id", `<div class="x" id="y">hi</div>`, `<div>hi</div>`},
+		{"img keeps safe attributes", `<img src="/a.png" alt="A" width="300" height="200" style="x" class="y">`, `<img src="https://example.com/a.png" alt="A" width="300" height="200"/>`},
+
+		// Tracking pixels
+		{"1x1 pixel", `<p>hi<img src="https://t.example/p.gif" width="1" height="1"></p>`, `<p>hi</p>`},
+		{"0x0 pixel", `<img src="https://t.example/p.gif" width="0" height="0">`, ``},
+		{"1px pixel", `<img src="https://t.example/p.gif" width="1px" height="1px">`, ``},
+		{"img without size kept", `<img src="https://example.com/a.png">`, `<img src="https://example.com/a.png"/>`},
+		{"img 1 wide but tall kept", `<img src="https://example.com/a.png" width="1" height="50">`, `<img src="https://example.com/a.png" width="1" height="50"/>`},
+		{"img without src", `<img alt="nothing">`, ``},
+
+		// Relative links
+		{"relative href", `<a href="other.html">x</a>`, `<a href="https://example.com/blog/other.html" rel="nofollow noopener noreferrer">x</a>`},
+		{"root-relative href", `<a href="/about">x</a>`, `<a href="https://example.com/about" rel="nofollow noopener noreferrer">x</a>`},
+		{"parent-relative href", `<a href="../up">x</a>`, `<a href="https://example.com/up" rel="nofollow noopener noreferrer">x</a>`},
+		{"protocol-relative src", `<img src="//cdn.example.com/a.png">`, `<img src="https://cdn.example.com/a.png"/>`},
+		{"fragment href", `<a href="#note">x</a>`, `<a href="https://example.com/blog/post.html#note" rel="nofollow noopener noreferrer">x</a>`},
+		{"link without href", `<a name="top">x</a>`, `<a>x</a>`},
+
+		// Nested and malformed markup
+		{"unclosed tags", `<p><b>bold <i>both`, `<p><b>bold <i>both</i></b></p>`},
+		{"misnested tags", `<b><i>x</b></i>`, `<b><i>x</i></b>`},
+		{"unknown element unwrapped", `<custom-tag><p>inside</p></custom-tag>`, `<p>inside</p>`},
+		{"script nested in allowed elements", `<div><p><span><script>alert(1)</script>ok</span></p></div>`, `<div><p><span>ok</span></p></div>`},
+		{"unwrapped element keeps allowed children", `<section><a href="/x" onclick="y">x</a></section>`, `<a href="https://example.com/x" rel="nofollow noopener noreferrer">x</a>`},
+		{"text is escaped", `1 < 2 & "quotes"`, `1 &lt; 2 &amp; &#34;quotes&#34;`},
+		{"attribute breakout", `<a href='https://example.com/"><script>alert(1)</script>'>x</a>`, `<a href="https://example.com/%22%3E%3Cscript%3Ealert%281%29%3C/script%3E" rel="nofollow noopener noreferrer">x</a>`},
+		{"stray end tags", `</p></div>text</b>`, `<p></p>text`},
+		{"full document", `<html><head><title>T</title></head><body><p>body</p></body></html>`, `<p>body</p>`},
+		{"empty", ``, ``},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := HTML(tt.src, base); got != tt.want {
+				t.Errorf("HTML(%q)\n got %q\nwant %q", tt.src, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestHTMLRemovesActiveContent checks the output of every fixture for
+// anything a browser could run, independently of the exact markup kept.
+func TestHTMLRemovesActiveContent(t *testing.T) {
+	fixtures := []string{
+		`<script>alert(1)</script>`,
+		`<img src=x onerror=alert(1)>`,
+		`<svg/onload=alert(1)>`,
+		`<a href="javascript:alert(1)">x</a>`,
+		`<a href=" &#14; javascript:alert(1)">x</a>`,
+		`<iframe srcdoc="<script>alert(1)</script>"></iframe>`,
+		`<object data="javascript:alert(1)"></object>`,
+		`<body onload=alert(1)>`,
+		`<div style="background:url(javascript:alert(1))">x</div>`,
+		`<a href="data:text/html,<script>alert(1)</script>">x</a>`,
+		`<img src="x" alt="x" onload="alert(1)" width="10">`,
+		`<<script>script>alert(1)//<</script>/script>`,
+		`<p><a href="jav&#x09;ascript:alert(1)">x</a></p>`,
+		`<link rel="stylesheet" href="https://evil.example/x.css">`,
+		`<details open ontoggle=alert(1)>`,
+	}
+
+	for _, src := range fixtures {
+		got := strings.ToLower(HTML(src, base))
+		for _, bad := range []string{"<script", "<iframe", "<object", "<svg", "<link", "javascript:", "data:", " on", "style="} {
+			if strings.Contains(got, bad) {
+				t.Errorf("HTML(%q) = %q, which contains %q", src, got, bad)
+			}
+		}
+	}
+}
+
+func TestHTMLWithoutBase(t *testing.T) {
+	tests := []struct {
+		base string
+		src  string
+		want string
+	}{
+		{"", `<a href="/about">x</a>`, `<a href="/about" rel="nofollow noopener noreferrer">x</a>`},
+		{"not a url", `<img src="a.png">`, `<img src="a.png"/>`},
+		{"", `<a href="javascript:alert(1)">x</a>`, `<a>x</a>`},
+	}
+
+	for _, tt := range tests {
+		if got := HTML(tt.src, tt.base); got != tt.want {
+			t.Errorf("HTML(%q, %q) = %q, want %q", tt.src, tt.base, got, tt.want)
+		}
+	}
+}
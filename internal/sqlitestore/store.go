@@ -0,0 +1,314 @@
+// Package sqlitestore implements database.Querier on top of the queries
+// generated for the SQLite schema, converting between the two packages'
+// types where SQLite's column types differ from Postgres'.
+package sqlitestore
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/google/uuid"
+	"github.com/josequiceno2000/gator/internal/database"
+	"github.com/josequiceno2000/gator/internal/sqlitedb"
+)
+
+type Store struct {
+	q *sqlitedb.Queries
+}
+
+var _ database.Querier = (*Store)(nil)
+
+func New(db sqlitedb.DBTX) *Store {
+	return &Store{q: sqlitedb.New(db)}
+}
+
+// SQLite has a single integer type, so nullable integers come back as
+// NullInt64 where Postgres uses NullInt32.
+
+func toNullInt32(n sql.NullInt64) sql.NullInt32 {
+	return sql.NullInt32{Int32: int32(n.Int64), Valid: n.Valid}
+}
+
+func toNullInt64(n sql.NullInt32) sql.NullInt64 {
+	return sql.NullInt64{Int64: int64(n.Int32), Valid: n.Valid}
+}
+
+func toFeed(f sqlitedb.Feed) database.Feed {
+	return database.Feed{
+		ID:                f.ID,
+		CreatedAt:         f.CreatedAt,
+		UpdatedAt:         f.UpdatedAt,
+		Name:              f.Name,
+		Url:               f.Url,
+		UserID:            f.UserID,
+		LastFetchedAt:     f.LastFetchedAt,
+		RetentionDays:     toNullInt32(f.RetentionDays),
+		RetentionMaxPosts: toNullInt32(f.RetentionMaxPosts),
+	}
+}
+
+func toRules(rules []sqlitedb.Rule) []database.Rule {
+	var items []database.Rule
+	for _, rule := range rules {
+		items = append(items, database.Rule(rule))
+	}
+	return items
+}
+
+func (s *Store) ClearFolder(ctx context.Context, arg database.ClearFolderParams) (int64, error) {
+	return s.q.ClearFolder(ctx, sqlitedb.ClearFolderParams(arg))
+}
+
+func (s *Store) CountFeedFollowers(ctx context.Context, feedID uuid.UUID) (int64, error) {
+	return s.q.CountFeedFollowers(ctx, feedID)
+}
+
+func (s *Store) CreateAPIToken(ctx context.Context, arg database.CreateAPITokenParams) (database.ApiToken, error) {
+	token, err := s.q.CreateAPIToken(ctx, sqlitedb.CreateAPITokenParams(arg))
+	return database.ApiToken(token), err
+}
+
+func (s *Store) CreateFeed(ctx context.Context, arg database.CreateFeedParams) (database.Feed, error) {
+	feed, err := s.q.CreateFeed(ctx, sqlitedb.CreateFeedParams(arg))
+	return toFeed(feed), err
+}
+
+func (s *Store) CreateFeedFollow(ctx context.Context, arg database.CreateFeedFollowParams) (database.CreateFeedFollowRow, error) {
+	row, err := s.q.CreateFeedFollow(ctx, sqlitedb.CreateFeedFollowParams(arg))
+	return database.CreateFeedFollowRow(row), err
+}
+
+func (s *Store) CreatePost(ctx context.Context, arg database.CreatePostParams) (database.Post, error) {
+	post, err := s.q.CreatePost(ctx, sqlitedb.CreatePostParams(arg))
+	return database.Post(post), err
+}
+
+func (s *Store) CreateRule(ctx context.Context, arg database.CreateRuleParams) (database.Rule, error) {
+	rule, err := s.q.CreateRule(ctx, sqlitedb.CreateRuleParams(arg))
+	return database.Rule(rule), err
+}
+
+func (s *Store) CreateUser(ctx context.Context, arg database.CreateUserParams) (database.User, error) {
+	user, err := s.q.CreateUser(ctx, sqlitedb.CreateUserParams(arg))
+	return database.User(user), err
+}
+
+func (s *Store) DeleteAPIToken(ctx context.Context, arg database.DeleteAPITokenParams) (int64, error) {
+	return s.q.DeleteAPIToken(ctx, sqlitedb.DeleteAPITokenParams(arg))
+}
+
+func (s *Store) DeleteAllUsers(ctx context.Context) error {
+	return s.q.DeleteAllUsers(ctx)
+}
+
+func (s *Store) DeleteFeedFollow(ctx context.Context, arg database.DeleteFeedFollowParams) error {
+	return s.q.DeleteFeedFollow(ctx, sqlitedb.DeleteFeedFollowParams(arg))
+}
+
+func (s *Store) DeletePosts(ctx context.Context, ids []uuid.UUID) (int64, error) {
+	return s.q.DeletePosts(ctx, ids)
+}
+
+func (s *Store) DeleteRule(ctx context.Context, arg database.DeleteRuleParams) (int64, error) {
+	return s.q.DeleteRule(ctx, sqlitedb.DeleteRuleParams(arg))
+}
+
+func (s *Store) GetAPITokensForUser(ctx context.Context, userID uuid.UUID) ([]database.ApiToken, error) {
+	tokens, err := s.q.GetAPITokensForUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var items []database.ApiToken
+	for _, token := range tokens {
+		items = append(items, database.ApiToken(token))
+	}
+	return items, nil
+}
+
+func (s *Store) GetFeedByUrl(ctx context.Context, url string) (database.Feed, error) {
+	feed, err := s.q.GetFeedByUrl(ctx, url)
+	return toFeed(feed), err
+}
+
+func (s *Store) GetFeedFetchTimes(ctx context.Context) ([]sql.NullTime, error) {
+	return s.q.GetFeedFetchTimes(ctx)
+}
+
+func (s *Store) GetFeedFollowsForUser(ctx context.Context, userID uuid.UUID) ([]database.GetFeedFollowsForUserRow, error) {
+	rows, err := s.q.GetFeedFollowsForUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var items []database.GetFeedFollowsForUserRow
+	for _, row := range rows {
+		items = append(items, database.GetFeedFollowsForUserRow(row))
+	}
+	return items, nil
+}
+
+func (s *Store) GetFeedNamesForCanonicalURLs(ctx context.Context, arg database.GetFeedNamesForCanonicalURLsParams) ([]database.GetFeedNamesForCanonicalURLsRow, error) {
+	rows, err := s.q.GetFeedNamesForCanonicalURLs(ctx, sqlitedb.GetFeedNamesForCanonicalURLsParams(arg))
+	if err != nil {
+		return nil, err
+	}
+
+	var items []database.GetFeedNamesForCanonicalURLsRow
+	for _, row := range rows {
+		items = append(items, database.GetFeedNamesForCanonicalURLsRow(row))
+	}
+	return items, nil
+}
+
+func (s *Store) GetFeedsWithUserNames(ctx context.Context) ([]database.GetFeedsWithUserNamesRow, error) {
+	rows, err := s.q.GetFeedsWithUserNames(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var items []database.GetFeedsWithUserNamesRow
+	for _, row := range rows {
+		items = append(items, database.GetFeedsWithUserNamesRow(row))
+	}
+	return items, nil
+}
+
+func (s *Store) GetIngestMuteRulesForFeed(ctx context.Context, feedID uuid.UUID) ([]database.GetIngestMuteRulesForFeedRow, error) {
+	rows, err := s.q.GetIngestMuteRulesForFeed(ctx, feedID)
+	if err != nil {
+		return nil, err
+	}
+
+	var items []database.GetIngestMuteRulesForFeedRow
+	for _, row := range rows {
+		items = append(items, database.GetIngestMuteRulesForFeedRow(row))
+	}
+	return items, nil
+}
+
+func (s *Store) GetNextFeedToFetch(ctx context.Context) (database.Feed, error) {
+	feed, err := s.q.GetNextFeedToFetch(ctx)
+	return toFeed(feed), err
+}
+
+func (s *Store) GetPostForUser(ctx context.Context, arg database.GetPostForUserParams) (database.GetPostForUserRow, error) {
+	row, err := s.q.GetPostForUser(ctx, sqlitedb.GetPostForUserParams(arg))
+	return database.GetPostForUserRow(row), err
+}
+
+func (s *Store) GetPostIDInRange(ctx context.Context, arg database.GetPostIDInRangeParams) (uuid.UUID, error) {
+	return s.q.GetPostIDInRange(ctx, sqlitedb.GetPostIDInRangeParams(arg))
+}
+
+func (s *Store) GetPostsForUser(ctx context.Context, arg database.GetPostsForUserParams) ([]database.GetPostsForUserRow, error) {
+	rows, err := s.q.GetPostsForUser(ctx, sqlitedb.GetPostsForUserParams{
+		UserID:         arg.UserID,
+		Folder:         arg.Folder,
+		FeedID:         arg.FeedID,
+		UnreadOnly:     arg.UnreadOnly,
+		SavedOnly:      arg.SavedOnly,
+		PublishedAfter: arg.PublishedAfter,
+		OldestFirst:    arg.OldestFirst,
+		Limit:          int64(arg.Limit),
+		Offset:         int64(arg.Offset),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var items []database.GetPostsForUserRow
+	for _, row := range rows {
+		items = append(items, database.GetPostsForUserRow(row))
+	}
+	return items, nil
+}
+
+func (s *Store) GetPrunablePosts(ctx context.Context, arg database.GetPrunablePostsParams) ([]database.GetPrunablePostsRow, error) {
+	rows, err := s.q.GetPrunablePosts(ctx, sqlitedb.GetPrunablePostsParams{
+		DefaultDays:     int64(arg.DefaultDays),
+		DefaultMaxPosts: int64(arg.DefaultMaxPosts),
+		Now:             arg.Now,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var items []database.GetPrunablePostsRow
+	for _, row := range rows {
+		items = append(items, database.GetPrunablePostsRow(row))
+	}
+	return items, nil
+}
+
+func (s *Store) GetRulesForUser(ctx context.Context, userID uuid.UUID) ([]database.Rule, error) {
+	rules, err := s.q.GetRulesForUser(ctx, userID)
+	return toRules(rules), err
+}
+
+func (s *Store) GetUnreadCountsForUser(ctx context.Context, userID uuid.UUID) ([]database.GetUnreadCountsForUserRow, error) {
+	rows, err := s.q.GetUnreadCountsForUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var items []database.GetUnreadCountsForUserRow
+	for _, row := range rows {
+		items = append(items, database.GetUnreadCountsForUserRow(row))
+	}
+	return items, nil
+}
+
+func (s *Store) GetUser(ctx context.Context, name string) (database.User, error) {
+	user, err := s.q.GetUser(ctx, name)
+	return database.User(user), err
+}
+
+func (s *Store) GetUserByAPIToken(ctx context.Context, tokenHash string) (database.User, error) {
+	user, err := s.q.GetUserByAPIToken(ctx, tokenHash)
+	return database.User(user), err
+}
+
+func (s *Store) GetUsers(ctx context.Context) ([]string, error) {
+	return s.q.GetUsers(ctx)
+}
+
+func (s *Store) MarkFeedFetched(ctx context.Context, id uuid.UUID) error {
+	return s.q.MarkFeedFetched(ctx, id)
+}
+
+func (s *Store) RenameFolder(ctx context.Context, arg database.RenameFolderParams) (int64, error) {
+	return s.q.RenameFolder(ctx, sqlitedb.RenameFolderParams(arg))
+}
+
+func (s *Store) SetFeedFollowDisplayName(ctx context.Context, arg database.SetFeedFollowDisplayNameParams) (int64, error) {
+	return s.q.SetFeedFollowDisplayName(ctx, sqlitedb.SetFeedFollowDisplayNameParams{
+		DisplayName: arg.DisplayName,
+		UserID:      arg.UserID,
+		Url:         arg.Url,
+	})
+}
+
+func (s *Store) SetFeedFollowFolder(ctx context.Context, arg database.SetFeedFollowFolderParams) (int64, error) {
+	return s.q.SetFeedFollowFolder(ctx, sqlitedb.SetFeedFollowFolderParams{
+		Folder: arg.Folder,
+		UserID: arg.UserID,
+		Url:    arg.Url,
+	})
+}
+
+func (s *Store) SetFeedRetention(ctx context.Context, arg database.SetFeedRetentionParams) error {
+	return s.q.SetFeedRetention(ctx, sqlitedb.SetFeedRetentionParams{
+		RetentionDays:     toNullInt64(arg.RetentionDays),
+		RetentionMaxPosts: toNullInt64(arg.RetentionMaxPosts),
+		ID:                arg.ID,
+	})
+}
+
+func (s *Store) SetPostRead(ctx context.Context, arg database.SetPostReadParams) error {
+	return s.q.SetPostRead(ctx, sqlitedb.SetPostReadParams(arg))
+}
+
+func (s *Store) SetPostSaved(ctx context.Context, arg database.SetPostSavedParams) error {
+	return s.q.SetPostSaved(ctx, sqlitedb.SetPostSavedParams(arg))
+}
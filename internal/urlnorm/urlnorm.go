@@ -0,0 +1,86 @@
+package urlnorm
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// trackingParams are query parameters that identify where a click came
+// from rather than what is being linked to.
+var trackingParams = map[string]bool{
+	"fbclid":  true,
+	"gclid":   true,
+	"dclid":   true,
+	"msclkid": true,
+	"yclid":   true,
+	"igshid":  true,
+	"mc_cid":  true,
+	"mc_eid":  true,
+	"_ga":     true,
+	"ref":     true,
+	"ref_src": true,
+}
+
+func isTrackingParam(key string) bool {
+	key = strings.ToLower(key)
+	return trackingParams[key] || strings.HasPrefix(key, "utm_")
+}
+
+// Clean removes tracking query parameters and the fragment from raw,
+// leaving the rest of the URL as published. Unparseable URLs are returned
+// unchanged.
+func Clean(raw string) string {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil {
+		return raw
+	}
+
+	u.Fragment = ""
+	u.RawFragment = ""
+
+	// Only re-encode the query when something was removed, so untouched
+	// URLs keep their original encoding
+	query := u.Query()
+	removed := false
+	for key := range query {
+		if isTrackingParam(key) {
+			query.Del(key)
+			removed = true
+		}
+	}
+	if removed {
+		u.RawQuery = query.Encode()
+	}
+
+	return u.String()
+}
+
+// Canonical returns a form of raw that is identical for URLs pointing at
+// the same article: tracking parameters and the fragment are dropped, the
+// scheme and host are lowercased, default ports and trailing slashes are
+// removed and the remaining query parameters are sorted.
+func Canonical(raw string) string {
+	u, err := url.Parse(Clean(raw))
+	if err != nil {
+		return raw
+	}
+
+	u.Scheme = strings.ToLower(u.Scheme)
+	u.Host = strings.ToLower(u.Host)
+	if port := u.Port(); (u.Scheme == "http" && port == "80") || (u.Scheme == "https" && port == "443") {
+		u.Host = u.Hostname()
+	}
+
+	u.Path = strings.TrimRight(u.Path, "/")
+	u.RawPath = ""
+
+	// Encode sorts by key, which is what makes the query canonical
+	query := u.Query()
+	for _, values := range query {
+		sort.Strings(values)
+	}
+	u.RawQuery = query.Encode()
+
+	return u.String()
+}
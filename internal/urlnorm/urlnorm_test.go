@@ -0,0 +1,80 @@
+package urlnorm
+
+import "testing"
+
+func TestClean(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+		want string
+	}{
+		{"unchanged", "https://example.com/posts/1", "https://example.com/posts/1"},
+		{"utm parameters", "https://example.com/a?utm_source=rss&utm_medium=feed", "https://example.com/a"},
+		{"uppercase utm parameter", "https://example.com/a?UTM_Campaign=x", "https://example.com/a"},
+		{"click ids", "https://example.com/a?fbclid=1&gclid=2&msclkid=3", "https://example.com/a"},
+		{"other parameters kept", "https://example.com/a?id=7&utm_source=rss&page=2", "https://example.com/a?id=7&page=2"},
+		{"fragment", "https://example.com/a#comments", "https://example.com/a"},
+		{"encoding kept when nothing removed", "https://example.com/a?q=a+b&z=1&a=2", "https://example.com/a?q=a+b&z=1&a=2"},
+		{"case kept", "HTTPS://Example.com/Post/", "https://Example.com/Post/"},
+		{"surrounding space", "  https://example.com/a  ", "https://example.com/a"},
+		{"unparseable", "https://exa mple.com:x/", "https://exa mple.com:x/"},
+		{"empty", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Clean(tt.raw); got != tt.want {
+				t.Errorf("Clean(%q) = %q, want %q", tt.raw, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCanonical(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+		want string
+	}{
+		{"unchanged", "https://example.com/posts/1", "https://example.com/posts/1"},
+		{"scheme and host lowercased", "HTTPS://Example.COM/Post", "https://example.com/Post"},
+		{"default https port", "https://example.com:443/a", "https://example.com/a"},
+		{"default http port", "http://example.com:80/a", "http://example.com/a"},
+		{"other port kept", "https://example.com:8443/a", "https://example.com:8443/a"},
+		{"http port on https kept", "https://example.com:80/a", "https://example.com:80/a"},
+		{"trailing slash", "https://example.com/a/", "https://example.com/a"},
+		{"root", "https://example.com/", "https://example.com"},
+		{"query sorted", "https://example.com/a?z=1&a=2&a=1", "https://example.com/a?a=1&a=2&z=1"},
+		{"tracking and fragment", "https://example.com/a/?utm_source=rss&id=3#top", "https://example.com/a?id=3"},
+		{"unparseable", "https://exa mple.com:x/", "https://exa mple.com:x/"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Canonical(tt.raw); got != tt.want {
+				t.Errorf("Canonical(%q) = %q, want %q", tt.raw, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCanonicalMatchesDuplicates(t *testing.T) {
+	// The same article as linked from different feeds
+	urls := []string{
+		"https://example.com/2024/05/post",
+		"https://Example.com/2024/05/post/",
+		"https://example.com:443/2024/05/post?utm_source=newsletter",
+		"https://example.com/2024/05/post#comments",
+		"HTTPS://example.com/2024/05/post?fbclid=abc",
+	}
+	want := Canonical(urls[0])
+	for _, u := range urls[1:] {
+		if got := Canonical(u); got != want {
+			t.Errorf("Canonical(%q) = %q, want %q", u, got, want)
+		}
+	}
+
+	if Canonical("https://example.com/a?id=1") == Canonical("https://example.com/a?id=2") {
+		t.Error("URLs that differ in a meaningful parameter have the same canonical form")
+	}
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+
+	"github.com/josequiceno2000/gator/internal/config"
+)
+
+// Diagnostics, such as what agg is fetching, are logged with log/slog to
+// stderr. Command output, and errors the user has to act on, are printed
+// directly so that stdout can be piped without log lines mixed in.
+
+// newLogger returns a logger writing to w at the given level ("debug",
+// "info", "warn" or "error") in the given format ("text" or "json"). Empty
+// values mean info and text.
+func newLogger(w io.Writer, level, format string) (*slog.Logger, error) {
+	var lvl slog.Level
+	if level != "" {
+		if err := lvl.UnmarshalText([]byte(level)); err != nil {
+			return nil, fmt.Errorf("invalid log_level %q (debug, info, warn or error)", level)
+		}
+	}
+
+	opts := &slog.HandlerOptions{Level: lvl}
+	switch format {
+	case "", "text":
+		return slog.New(slog.NewTextHandler(w, opts)), nil
+	case "json":
+		return slog.New(slog.NewJSONHandler(w, opts)), nil
+	default:
+		return nil, fmt.Errorf("invalid log_format %q (text or json)", format)
+	}
+}
+
+// setupLogging makes the logger configured by cfg the default. Invalid
+// settings fall back to the defaults rather than stopping the command,
+// since fixing them may need a command to run.
+func setupLogging(cfg *config.Config) {
+	logger, err := newLogger(os.Stderr, cfg.LogLevel, cfg.LogFormat)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v; logging at info level as text\n", err)
+		logger, _ = newLogger(os.Stderr, "", "")
+	}
+	slog.SetDefault(logger)
+}
+
+// fatalf prints an error for the user and exits.
+func fatalf(format string, args ...any) {
+	fmt.Fprintf(os.Stderr, "Error: "+format+"\n", args...)
+	os.Exit(1)
+}
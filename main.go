@@ -4,78 +4,138 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"flag"
 	"fmt"
-	"log"
+	"io"
+	"log/slog"
 	"os"
+	"os/signal"
 	"strconv"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/josequiceno2000/gator/internal/config"
 	"github.com/josequiceno2000/gator/internal/database"
+	"github.com/josequiceno2000/gator/internal/render"
+	"github.com/josequiceno2000/gator/internal/sanitize"
+	"github.com/josequiceno2000/gator/internal/urlnorm"
 	_ "github.com/lib/pq"
 )
 
-func scrapeFeeds(s *state) {
-	feed, err := s.DB.GetNextFeedToFetch(context.Background())
+// scrapeFeeds fetches the feed that was fetched longest ago and saves its
+// new posts. Cancelling ctx aborts the fetch, or stops saving posts after
+// the current one, so that no write is cut off halfway. The returned error
+// means the feed couldn't be fetched at all; problems with single posts
+// are only logged.
+func scrapeFeeds(ctx context.Context, s *state, st *aggStatus) error {
+	// Database writes run to completion even once ctx is cancelled
+	dbCtx := context.WithoutCancel(ctx)
+
+	feed, err := s.DB.GetNextFeedToFetch(ctx)
+	if err == sql.ErrNoRows {
+		slog.Debug("no feeds to fetch")
+		return nil
+	}
 	if err != nil {
-		log.Printf("scrapeFeeds: failed to get next feed: %v", err)
+		slog.Error("failed to get next feed", "err", err)
+		return fmt.Errorf("failed to get next feed: %w", err)
 	}
 
-	log.Printf("scrapeFeeds: fetching feed: %s", feed.Url)
+	logger := slog.With("feed_id", feed.ID, "url", feed.Url)
+	logger.Debug("fetching feed")
+	st.fetchStarted(feed.Url)
+	start := time.Now()
 
-	err = s.DB.MarkFeedFetched(context.Background(), feed.ID)
+	err = s.DB.MarkFeedFetched(dbCtx, feed.ID)
 	if err != nil {
-		log.Printf("scrapeFeeds: failed to mark feed as fetched: %v", err)
-		return
+		logger.Error("failed to mark feed as fetched", "err", err)
+		return fmt.Errorf("failed to mark feed as fetched: %w", err)
 	}
 
-	rssFeed, err := fetchFeed(context.Background(), feed.Url)
+	rssFeed, err := fetchFeed(ctx, feed.Url)
 	if err != nil {
-		log.Printf("scrapeFeeds: failed to fetch feed: %v", err)
-		return
+		logger.Error("failed to fetch feed", "err", err, "duration", time.Since(start))
+		return fmt.Errorf("failed to fetch feed: %w", err)
 	}
 
-	for _, item := range rssFeed.Channel.Item {
+	muter, err := loadIngestMuter(s, feed.ID)
+	if err != nil {
+		logger.Warn("failed to load ingest rules", "err", err)
+	}
+
+	inserted, duplicates := 0, 0
+	for i, item := range rssFeed.Channel.Item {
+		if ctx.Err() != nil {
+			logger.Warn("stopped before saving all posts", "remaining", len(rssFeed.Channel.Item)-i)
+			return nil
+		}
+
 		publishedAt, err := time.Parse(time.RFC3339, item.PubDate)
 		if err != nil {
 			publishedAt, err = time.Parse(time.RFC1123Z, item.PubDate)
 			if err != nil {
-				log.Printf("scrapeFeeds: failed to parse published_at: %v", err)
+				logger.Warn("skipping post without a valid published date", "post_url", item.Link, "pub_date", item.PubDate)
+				postsTotal.Inc("invalid")
 				continue
 			}
 		}
 
+		// Descriptions come from third parties, so only store an allowlisted subset of HTML
 		var description sql.NullString
-		if item.Description != "" {
-			description.String = item.Description
+		if cleaned := sanitize.HTML(item.Description, item.Link); cleaned != "" {
+			description.String = cleaned
 			description.Valid = true
 		} else {
 			description.Valid = false
 		}
 
-		_, err = s.DB.CreatePost(context.Background(), database.CreatePostParams{
+		if muter.muted(postFields{
+			Title: item.Title,
+			Description: description.String,
+			Author: item.author(),
+		}) {
+			postsTotal.Inc("muted")
+			continue
+		}
+
+		_, err = s.DB.CreatePost(dbCtx, database.CreatePostParams{
 			ID: uuid.New(),
 			CreatedAt: time.Now().UTC(),
 			UpdatedAt: time.Now().UTC(),
 			Title: item.Title,
-			Url: item.Link,
+			Url: urlnorm.Clean(item.Link),
 			Description: description,
 			PublishedAt: publishedAt.UTC(),
 			FeedID: feed.ID,
+			Author: sql.NullString{String: item.author(), Valid: item.author() != ""},
+			CanonicalUrl: urlnorm.Canonical(item.Link),
 		})
 		if err != nil {
-			if strings.Contains(err.Error(), "duplicate key value violates unique constraint") {
+			if isUniqueViolation(err) {
+				duplicates++
+				postsTotal.Inc("duplicate")
 				continue
 			}
-			log.Printf("scrapeFeeds: failed to create post: %v", err)
+			logger.Error("failed to save post", "post_url", item.Link, "err", err)
+			postsTotal.Inc("failed")
+			continue
 		}
+		inserted++
+		postsTotal.Inc("inserted")
 	}
+
+	logger.Info("fetched feed", "posts", len(rssFeed.Channel.Item), "inserted", inserted, "duplicates", duplicates, "duration", time.Since(start))
+	return nil
 }
 
 func middlewareLoggedIn(handler func(s *state, cmd command, user database.User) error) func(*state, command) error {
 	return func(s *state, cmd command) error {
+		if s.CfgPointer.CurrentUsername == "" {
+			return fmt.Errorf("%s: not logged in; run `gator login <name>` first", cmd.Name)
+		}
+
 		user, err := s.DB.GetUser(context.Background(), s.CfgPointer.CurrentUsername)
 		if err != nil {
 			if err == sql.ErrNoRows {
@@ -89,6 +149,10 @@ func middlewareLoggedIn(handler func(s *state, cmd command, user database.User)
 }
 
 func handlerBrowse(s *state, cmd command, user database.User) error {
+	folder := cmd.stringFlag("folder")
+	showMuted := cmd.boolFlag("show-muted")
+	full := cmd.boolFlag("full")
+
 	limit := int32(2)
 
 	if len(cmd.Arguments) > 0 {
@@ -99,26 +163,139 @@ func handlerBrowse(s *state, cmd command, user database.User) error {
 		limit = int32(parsedLimit)
 	}
 
-	posts, err := s.DB.GetPostsForUser(context.Background(), database.GetPostsForUserParams{
+	rules, err := s.DB.GetRulesForUser(context.Background(), user.ID)
+	if err != nil {
+		return fmt.Errorf("browse: failed to get rules: %w", err)
+	}
+
+	compiledRules, err := compileRules(rules)
+	if err != nil {
+		return fmt.Errorf("browse: %w", err)
+	}
+
+	// Muted and duplicate posts are dropped after the query, so keep paging until the limit is filled
+	type browseEntry struct {
+		post database.GetPostsForUserRow
+		muted bool
+		highlighted bool
+	}
+
+	var entries []browseEntry
+	seen := make(map[string]bool)
+	for offset := int32(0); len(entries) < int(limit); offset += limit {
+		posts, err := s.DB.GetPostsForUser(context.Background(), database.GetPostsForUserParams{
+			UserID: user.ID,
+			Folder: sql.NullString{String: folder, Valid: folder != ""},
+			Limit: limit,
+			Offset: offset,
+		})
+		if err != nil {
+			return fmt.Errorf("browse: failed to get posts: %w", err)
+		}
+
+		for _, post := range posts {
+			if seen[post.CanonicalUrl] {
+				continue
+			}
+
+			muted, highlighted := classifyPost(compiledRules, postFields{
+				Title: post.Title,
+				Description: post.Description.String,
+				Author: post.Author.String,
+				FeedName: post.FeedName,
+			})
+			if muted && !showMuted {
+				continue
+			}
+
+			seen[post.CanonicalUrl] = true
+			entries = append(entries, browseEntry{post: post, muted: muted, highlighted: highlighted})
+			if len(entries) == int(limit) {
+				break
+			}
+		}
+
+		if len(posts) < int(limit) {
+			break
+		}
+	}
+
+	// The same article can arrive through several feeds, so list every feed it came from
+	canonicalURLs := make([]string, len(entries))
+	for i, entry := range entries {
+		canonicalURLs[i] = entry.post.CanonicalUrl
+	}
+
+	sources, err := s.DB.GetFeedNamesForCanonicalURLs(context.Background(), database.GetFeedNamesForCanonicalURLsParams{
 		UserID: user.ID,
-		Limit: limit,
+		Folder: sql.NullString{String: folder, Valid: folder != ""},
+		CanonicalUrls: canonicalURLs,
 	})
 	if err != nil {
-		return fmt.Errorf("browse: failed to get posts: %w", err)
+		return fmt.Errorf("browse: failed to get post sources: %w", err)
 	}
 
-	for _, post := range posts {
-		fmt.Printf("Title: %s\nURL: %s\nPublished: %s\n\n", post.Title, post.Url, post.PublishedAt)
+	feedNames := make(map[string][]string)
+	for _, source := range sources {
+		feedNames[source.CanonicalUrl] = append(feedNames[source.CanonicalUrl], source.FeedName)
+	}
+
+	for _, entry := range entries {
+		post := entry.post
+
+		title := post.Title
+		if entry.highlighted {
+			title = "[highlight] " + title
+		}
+		if entry.muted {
+			title = "[muted] " + title
+		}
+
+		feeds := post.FeedName
+		if names := feedNames[post.CanonicalUrl]; len(names) > 1 {
+			feeds = strings.Join(names, ", ")
+		}
+
+		fmt.Printf("Title: %s\nFeed: %s\nURL: %s\nPublished: %s\nID: %s\n\n", title, feeds, post.Url, post.PublishedAt, post.ID)
+
+		if full && post.Description.Valid {
+			fmt.Printf("%s\n\n", render.Text(post.Description.String, terminalWidth()))
+		}
 	}
 
 	return nil
 }
 
-func handlerUnfollow(s *state, cmd command, user database.User) error {
-	if len(cmd.Arguments) < 1 {
-		return errors.New("unfollow: url argument is required")
+func handlerRenameFollow(s *state, cmd command, user database.User) error {
+	url := cmd.Arguments[0]
+
+	// Omitting the name clears the override and falls back to the feed's own name
+	var displayName sql.NullString
+	if len(cmd.Arguments) > 1 {
+		displayName = sql.NullString{String: cmd.Arguments[1], Valid: true}
 	}
 
+	n, err := s.DB.SetFeedFollowDisplayName(context.Background(), database.SetFeedFollowDisplayNameParams{
+		UserID: user.ID,
+		Url: url,
+		DisplayName: displayName,
+	})
+	if err != nil {
+		return fmt.Errorf("rename-follow: failed to rename feed: %w", err)
+	}
+	if n == 0 {
+		return fmt.Errorf("rename-follow: not following feed with URL: %s", url)
+	}
+
+	if displayName.Valid {
+		fmt.Printf("Renamed feed %s to: %s\n", url, displayName.String)
+	} else {
+		fmt.Printf("Cleared custom name for feed: %s\n", url)
+	}
+	return nil
+}
+
+func handlerUnfollow(s *state, cmd command, user database.User) error {
 	url := cmd.Arguments[0]
 
 	err := s.DB.DeleteFeedFollow(context.Background(), database.DeleteFeedFollowParams{
@@ -145,6 +322,11 @@ func handlerFollowing(s *state, cmd command, user database.User) error {
 		return fmt.Errorf("following, failed to get feed follows: %w", err)
 	}
 
+	if cmd.boolFlag("tree") {
+		printFollowingTree(feedFollows)
+		return nil
+	}
+
 	for _, ff := range feedFollows {
 		fmt.Println(ff.FeedName)
 	}
@@ -153,10 +335,6 @@ func handlerFollowing(s *state, cmd command, user database.User) error {
 }
 
 func handlerFollow(s *state, cmd command, user database.User) error {
-	if len(cmd.Arguments) < 1 {
-		return errors.New("follow: url argument is required")
-	}
-
 	url := cmd.Arguments[0]
 
 	user, err := s.DB.GetUser(context.Background(), s.CfgPointer.CurrentUsername)
@@ -199,10 +377,6 @@ func handlerFeeds(s * state, cmd command) error {
 }
 
 func handlerAddFeed(s *state, cmd command, user database.User) error {
-	if len(cmd.Arguments) < 2 {
-		return errors.New("addfeed: name and url arguments are required")
-	}
-
 	name := cmd.Arguments[0]
 	url := cmd.Arguments[1]
 
@@ -214,59 +388,141 @@ func handlerAddFeed(s *state, cmd command, user database.User) error {
 		return fmt.Errorf("addfeed: failed to get user: %w", err)
 	}
 
-	feed, err := s.DB.CreateFeed(context.Background(), database.CreateFeedParams{
-        ID:        uuid.New(),
-        CreatedAt: time.Now().UTC(),
-        UpdatedAt: time.Now().UTC(),
-        Name:      name,
-        Url:       url,
-        UserID:    user.ID,
-    })
-
-    if err != nil {
-        return fmt.Errorf("addfeed: failed to create feed: %w", err)
-    }
-
-	_, err = s.DB.CreateFeedFollow(context.Background(), database.CreateFeedFollowParams{
-		ID: uuid.New(),
-		CreatedAt: time.Now().UTC(),
-		UpdatedAt: time.Now().UTC(),
-		UserID: user.ID,
-		FeedID: feed.ID,
-	})
-
+	feed, err := addFeed(context.Background(), s, user.ID, name, url)
 	if err != nil {
-		return fmt.Errorf("addfeed: failed to create feed follow: %w", err)
+		return fmt.Errorf("addfeed: %w", err)
 	}
 
 	fmt.Printf("Feed created and dollowed: %+v\n", feed)
 	return nil
 }
 
+// addFeed creates a feed and makes userID follow it. Both happen together
+// so a failed follow doesn't leave behind a feed nobody follows.
+func addFeed(ctx context.Context, s *state, userID uuid.UUID, name, url string) (database.Feed, error) {
+	var feed database.Feed
+	err := s.withTx(ctx, func(q database.Querier) error {
+		var err error
+		feed, err = q.CreateFeed(ctx, database.CreateFeedParams{
+			ID: uuid.New(),
+			CreatedAt: time.Now().UTC(),
+			UpdatedAt: time.Now().UTC(),
+			Name: name,
+			Url: url,
+			UserID: userID,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create feed: %w", err)
+		}
+
+		_, err = q.CreateFeedFollow(ctx, database.CreateFeedFollowParams{
+			ID: uuid.New(),
+			CreatedAt: time.Now().UTC(),
+			UpdatedAt: time.Now().UTC(),
+			UserID: userID,
+			FeedID: feed.ID,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create feed follow: %w", err)
+		}
+		return nil
+	})
+	return feed, err
+}
+
 func handlerAgg(s *state, cmd command) error {
-	if len(cmd.Arguments) < 1 {
-		return errors.New("agg: time_between_reqs argument is required")
-	}
+	pruneEvery := cmd.durationFlag("prune-every")
+	listen := cmd.stringFlag("listen")
+	readyWithin := cmd.durationFlag("ready-within")
 
 	timeBetweenRequests, err := time.ParseDuration(cmd.Arguments[0])
 	if err != nil {
 		return fmt.Errorf("agg: invalid duration: %w", err)
 	}
+	if readyWithin == 0 {
+		readyWithin = 3 * timeBetweenRequests
+	}
 
-	log.Printf("agg: collecting feeds every %s", timeBetweenRequests)
+	st := newAggStatus(timeBetweenRequests)
+	if listen != "" {
+		srv, err := startHealthServer(listen, s, st, readyWithin)
+		if err != nil {
+			return fmt.Errorf("agg: failed to listen: %w", err)
+		}
+		defer stopServer(srv)
+	}
+
+	// SIGINT and SIGTERM stop agg after the work in progress is finished
+	// or aborted; a second signal kills it outright
+	ctx, stop := signal.NotifyContext(cmd.Context, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	context.AfterFunc(ctx, stop)
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	slog.Info("collecting feeds", "interval", timeBetweenRequests)
 
 	ticker := time.NewTicker(timeBetweenRequests)
 	defer ticker.Stop()
 
-	scrapeFeeds(s)
+	// A nil channel never fires, which leaves pruning off
+	var pruneTick <-chan time.Time
+	if pruneEvery > 0 {
+		slog.Info("pruning posts", "interval", pruneEvery)
+		pruneTicker := time.NewTicker(pruneEvery)
+		defer pruneTicker.Stop()
+		pruneTick = pruneTicker.C
+	}
 
-	for range ticker.C {
-		scrapeFeeds(s)
+	scrape := func() {
+		st.cycleDone(scrapeFeeds(ctx, s, st))
 	}
 
+	scrape()
+
+	// Checking ctx first keeps a tick that is ready at the same time as the
+	// shutdown from starting new work
+	for ctx.Err() == nil {
+		select {
+		case <-ctx.Done():
+		case <-hup:
+			reloadConfig(s)
+		case <-pruneTick:
+			pruneOnce(ctx, s)
+		case <-ticker.C:
+			scrape()
+		}
+	}
+
+	slog.Info("shutting down")
 	return nil
 }
 
+// reloadConfig re-reads the config file in place, keeping the current
+// config if the file can't be read. The database connection stays open,
+// so a changed db_url only takes effect when agg is restarted.
+func reloadConfig(s *state) {
+	profile := ""
+	if s.CfgPointer.Source("profile") == config.SourceFlag {
+		profile = s.CfgPointer.Profile
+	}
+
+	cfg, err := config.Read(s.CfgPointer.Path(), profile)
+	if err != nil {
+		slog.Error("failed to reload config, keeping the current one", "err", err)
+		return
+	}
+
+	if cfg.DBURL != s.CfgPointer.DBURL {
+		slog.Warn("db_url changed; restart agg to use the new database")
+	}
+	*s.CfgPointer = cfg
+	setupLogging(s.CfgPointer)
+	slog.Info("reloaded config", "path", cfg.Path())
+}
+
 func handlerUsers(s *state, cmd command) error {
 	users, err := s.DB.GetUsers(context.Background())
 	if err != nil {
@@ -284,10 +540,6 @@ func handlerUsers(s *state, cmd command) error {
 }
 
 func handlerRegister(s *state, cmd command) error {
-	if len(cmd.Arguments) == 0 {
-		return errors.New("register command requires a username argument")
-	}
-
 	username := cmd.Arguments[0]
 	userID := uuid.New()
 	now := time.Now().UTC()
@@ -312,13 +564,18 @@ func handlerRegister(s *state, cmd command) error {
 	}
 
 	fmt.Printf("User '%s' registered successfully.\n", username)
-	log.Printf("Registered user: %+v\n", user)
+	slog.Debug("registered user", "user_id", user.ID, "name", user.Name)
 
 	return nil
 }
 
 func handlerReset(s *state, cmd command) error {
-	err := s.DB.DeleteAllUsers((context.Background()))
+	// Everything else cascades from users; the transaction makes sure the
+	// cascades and the delete land together or not at all
+	ctx := context.Background()
+	err := s.withTx(ctx, func(q database.Querier) error {
+		return q.DeleteAllUsers(ctx)
+	})
 	if err != nil {
 		return fmt.Errorf("reset: failed to delete all users: %w", err)
 	}
@@ -326,49 +583,340 @@ func handlerReset(s *state, cmd command) error {
 	return nil
 }
 
+// registerCommands adds every gator command to c.
+func registerCommands(c *commands) {
+	c.register(commandSpec{
+		Name: "help",
+		Usage: "[command]",
+		Summary: "Show the list of commands or help for one command",
+		MaxArgs: 1,
+		NoDatabase: true,
+		Complete: func(s *state, args []string) []string {
+			if len(args) > 0 {
+				return nil
+			}
+			return c.names()
+		},
+		Handler: handlerHelp(c),
+	})
+	c.register(commandSpec{
+		Name: "register",
+		Usage: "<name>",
+		Summary: "Create a user and log in as them",
+		MinArgs: 1,
+		MaxArgs: 1,
+		Handler: handlerRegister,
+	})
+	c.register(commandSpec{
+		Name: "login",
+		Usage: "<name>",
+		Summary: "Log in as an existing user",
+		MinArgs: 1,
+		MaxArgs: 1,
+		Complete: completeUsers,
+		Handler: handlerLogin,
+	})
+	c.register(commandSpec{
+		Name: "users",
+		Summary: "List all users",
+		Handler: handlerUsers,
+	})
+	c.register(commandSpec{
+		Name: "reset",
+		Summary: "Delete all users and their data",
+		Handler: handlerReset,
+	})
+	c.register(commandSpec{
+		Name: "agg",
+		Usage: "<time_between_reqs>",
+		Summary: "Fetch feeds continuously, one every time_between_reqs (e.g. 1m)",
+		Flags: func(fs *flag.FlagSet) {
+			fs.Duration("prune-every", 0, "also prune old posts at this interval (0 disables pruning)")
+			fs.String("listen", "", "serve /healthz, /readyz, /status and /metrics on this address (e.g. :8080)")
+			fs.Duration("ready-within", 0, "how recently a fetch cycle must have succeeded for /readyz (default 3x time_between_reqs)")
+		},
+		MinArgs: 1,
+		MaxArgs: 1,
+		Handler: handlerAgg,
+	})
+	c.register(commandSpec{
+		Name: "addfeed",
+		Usage: "<name> <url>",
+		Summary: "Add a feed and follow it",
+		MinArgs: 2,
+		MaxArgs: 2,
+		UserHandler: handlerAddFeed,
+	})
+	c.register(commandSpec{
+		Name: "feeds",
+		Summary: "List all feeds",
+		Handler: handlerFeeds,
+	})
+	c.register(commandSpec{
+		Name: "follow",
+		Usage: "<url>",
+		Summary: "Follow a feed someone has added",
+		MinArgs: 1,
+		MaxArgs: 1,
+		Complete: completeFeedURLs,
+		UserHandler: handlerFollow,
+	})
+	c.register(commandSpec{
+		Name: "following",
+		Summary: "List the feeds you follow",
+		Flags: func(fs *flag.FlagSet) {
+			fs.Bool("tree", false, "group followed feeds by folder")
+		},
+		UserHandler: handlerFollowing,
+	})
+	c.register(commandSpec{
+		Name: "unfollow",
+		Usage: "<url>",
+		Summary: "Stop following a feed",
+		MinArgs: 1,
+		MaxArgs: 1,
+		Complete: completeFollowedURLs,
+		UserHandler: handlerUnfollow,
+	})
+	c.register(commandSpec{
+		Name: "rename-follow",
+		Usage: "<url> [name]",
+		Summary: "Give a followed feed your own name, or clear it",
+		MinArgs: 1,
+		MaxArgs: 2,
+		Complete: completeFollowedURLs,
+		UserHandler: handlerRenameFollow,
+	})
+	c.register(commandSpec{
+		Name: "browse",
+		Usage: "[limit]",
+		Summary: "Print the newest posts from the feeds you follow",
+		Flags: func(fs *flag.FlagSet) {
+			fs.String("folder", "", "only show posts from feeds in this folder")
+			fs.Bool("show-muted", false, "include posts hidden by mute rules")
+			fs.Bool("full", false, "print each post's description")
+		},
+		MaxArgs: 1,
+		Complete: completeBrowse,
+		UserHandler: handlerBrowse,
+	})
+	c.register(commandSpec{
+		Name: "show",
+		Usage: "<post-id>",
+		Summary: "Print a post in full",
+		MinArgs: 1,
+		MaxArgs: 1,
+		UserHandler: handlerShow,
+	})
+	c.register(commandSpec{
+		Name: "folder",
+		Summary: "Organize followed feeds into folders",
+		Subcommands: []subcommandSpec{
+			{"add", "<folder> <url>", "Move a followed feed into a folder"},
+			{"rm", "<folder> [url]", "Take one or all feeds out of a folder"},
+			{"mv", "<old> <new>", "Rename a folder"},
+		},
+		Complete: completeFolder,
+		UserHandler: handlerFolder,
+	})
+	c.register(commandSpec{
+		Name: "rules",
+		Summary: "Mute or highlight posts matching a pattern",
+		Subcommands: []subcommandSpec{
+			{"add", "[--scope field] [--regex] [--ingest] <mute|highlight> <pattern>", "Add a rule"},
+			{"list", "", "List your rules"},
+			{"rm", "<id>", "Delete a rule"},
+		},
+		UserHandler: handlerRules,
+	})
+	c.register(commandSpec{
+		Name: "token",
+		Summary: "Manage API tokens for `gator serve`",
+		Subcommands: []subcommandSpec{
+			{"create", "<name>", "Create a token and print it"},
+			{"list", "", "List your tokens"},
+			{"rm", "<name>", "Revoke a token"},
+		},
+		Complete: completeToken,
+		UserHandler: handlerToken,
+	})
+	c.register(commandSpec{
+		Name: "serve",
+		Summary: "Serve the JSON API over HTTP",
+		Flags: func(fs *flag.FlagSet) {
+			fs.String("listen", ":8080", "address to listen on")
+		},
+		MaxArgs: 0,
+		Handler: handlerServe,
+	})
+	c.register(commandSpec{
+		Name: "tui",
+		Summary: "Read posts in a terminal UI",
+		Flags: func(fs *flag.FlagSet) {
+			fs.Duration("refresh", time.Minute, "how often to reload feeds and posts")
+		},
+		UserHandler: handlerTui,
+	})
+	c.register(commandSpec{
+		Name: "prune",
+		Summary: "Delete posts outside their feed's retention policy",
+		Flags: func(fs *flag.FlagSet) {
+			fs.Bool("dry-run", false, "list the posts that would be deleted without deleting them")
+		},
+		Handler: handlerPrune,
+	})
+	c.register(commandSpec{
+		Name: "retention",
+		Usage: "<url>",
+		Summary: "Show or override a feed's retention policy",
+		Flags: func(fs *flag.FlagSet) {
+			fs.Int("days", -1, "keep posts for this many days (0 keeps them forever)")
+			fs.Int("max-posts", -1, "keep at most this many posts (0 means no limit)")
+			fs.Bool("reset", false, "remove the overrides and use the global defaults")
+		},
+		MinArgs: 1,
+		MaxArgs: 1,
+		UserHandler: handlerRetention,
+	})
+	c.register(commandSpec{
+		Name: "migrate",
+		Summary: "Manage the database schema",
+		Subcommands: []subcommandSpec{
+			{"up", "", "Apply all pending migrations"},
+			{"down", "", "Roll back the latest migration"},
+			{"status", "", "List applied and pending migrations"},
+			{"redo", "", "Roll back the latest migration and apply it again"},
+		},
+		AnySchema: true,
+		Handler: handlerMigrate,
+	})
+	c.register(commandSpec{
+		Name: "config",
+		Summary: "Show, create or check the config file",
+		Subcommands: []subcommandSpec{
+			{"show", "", "Print the effective settings and where they came from"},
+			{"init", "[--db-url url] [--user name] [--force] [--no-check]", "Write a new config file"},
+			{"validate", "", "Check the config file and database"},
+		},
+		NoDatabase: true,
+		Handler: handlerConfig,
+	})
+	c.register(commandSpec{
+		Name: "profile",
+		Summary: "Manage named config profiles",
+		Subcommands: []subcommandSpec{
+			{"list", "", "List profiles"},
+			{"use", "<name>", "Switch to a profile"},
+			{"add", "--db-url url [--user name] <name>", "Add a profile"},
+			{"rm", "<name>", "Delete a profile"},
+		},
+		NoDatabase: true,
+		Complete: completeProfile,
+		Handler: handlerProfile,
+	})
+	c.register(commandSpec{
+		Name: "shell",
+		Usage: "[file]",
+		Summary: "Run commands interactively, or one per line from a file or stdin",
+		Flags: func(fs *flag.FlagSet) {
+			fs.Bool("keep-going", false, "run the rest of a script after a command fails")
+		},
+		MaxArgs: 1,
+		NoDatabase: true,
+		AnySchema: true,
+		Handler: handlerShell(c),
+	})
+	c.register(commandSpec{
+		Name: "completion",
+		Usage: "<bash|zsh|fish>",
+		Summary: "Print a shell completion script",
+		MinArgs: 1,
+		MaxArgs: 1,
+		NoDatabase: true,
+		Complete: completeShells,
+		Handler: handlerCompletion,
+	})
+	c.register(commandSpec{
+		Name: "__complete",
+		Summary: "Print completions for the words typed so far; used by the completion scripts",
+		MaxArgs: -1,
+		NoDatabase: true,
+		RawArgs: true,
+		Hidden: true,
+		Handler: handlerComplete(c),
+	})
+}
+
 func main() {
-	cfg, err := config.Read()
-	if err != nil {
-		log.Fatalf("Error reading config: %v", err)
+	cmdRegistry := commands{}
+	registerCommands(&cmdRegistry)
+
+	// Global flags come before the command name
+	globalFlags := flag.NewFlagSet("gator", flag.ContinueOnError)
+	globalFlags.SetOutput(io.Discard)
+	configPath := globalFlags.String("config", "", "path to the config file")
+	profile := globalFlags.String("profile", "", "config profile to use for this command")
+	if err := globalFlags.Parse(os.Args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			printHelp(os.Stdout, &cmdRegistry)
+			return
+		}
+		fmt.Fprintf(os.Stderr, "Error: %v (run `gator help` for usage)\n", err)
+		os.Exit(2)
 	}
+	args := globalFlags.Args()
 
-	// Open db connection
-	db, err := sql.Open("postgres", cfg.DBURL)
-	if err != nil {
-		log.Fatalf("Error opening db connectio: %v", err)
+	if len(args) < 1 {
+		printHelp(os.Stderr, &cmdRegistry)
+		os.Exit(2)
 	}
-	defer db.Close()
 
-	// Create database queries instance
-	dbQueries := database.New(db)
+	cmd := command{Name: args[0], Arguments: args[1:], Context: context.Background()}
 
-	appState := state{DB:dbQueries, CfgPointer: &cfg}
-	
-	cmdRegistry := commands{}
-	cmdRegistry.register("login", handlerLogin)
-	cmdRegistry.register("register", handlerRegister)
-	cmdRegistry.register("reset", handlerReset)
-	cmdRegistry.register("users", handlerUsers)
-	cmdRegistry.register("agg", handlerAgg)
-	cmdRegistry.register("addfeed", middlewareLoggedIn(handlerAddFeed))
-	cmdRegistry.register("feeds", handlerFeeds)
-	cmdRegistry.register("follow", middlewareLoggedIn(handlerFollow))
-	cmdRegistry.register("following", middlewareLoggedIn(handlerFollowing))
-	cmdRegistry.register("unfollow", middlewareLoggedIn(handlerUnfollow))
-	cmdRegistry.register("browse", middlewareLoggedIn(handlerBrowse))
+	spec, err := cmdRegistry.lookup(cmd.Name)
+	if err != nil {
+		fatalf("%v", err)
+	}
+	// Commands that don't touch the database, and help for any command,
+	// must work before gator is configured, and are how a broken config
+	// gets fixed
+	needsDB := !spec.NoDatabase && !wantsHelp(cmd.Arguments)
 
-	if len(os.Args) < 2 {
-		fmt.Println("Error: not enough arguments provided")
-		os.Exit(1)
+	cfg, err := config.Read(*configPath, *profile)
+	setupLogging(&cfg)
+	if err != nil && needsDB {
+		fatalf("failed to read config: %v (run `gator config validate` to check it or `gator config init` to recreate it)", err)
 	}
+	if cfg.DBURL == "" && needsDB {
+		fatalf("no db_url configured; run `gator config init` or set %s", config.EnvDBURL)
+	}
+
+	appState := state{CfgPointer: &cfg}
+
+	// Open db connection
+	db, dbBackend, err := openDatabase(cfg.DBURL)
+	if err != nil {
+		// config validate reports a bad db_url itself
+		if needsDB {
+			fatalf("failed to open database: %v", err)
+		}
+	} else {
+		defer db.Close()
 
-	cmdName := os.Args[1]
-	cmdArgs := os.Args[2:]
+		// Create database queries instance
+		appState.DB = dbBackend.queries(db)
+		appState.Conn = db
+		appState.Backend = dbBackend
+	}
 
-	cmd := command{Name: cmdName, Arguments: cmdArgs}
+	if needsDB && !spec.AnySchema {
+		if err := checkSchemaVersion(db, dbBackend); err != nil {
+			fatalf("%v", err)
+		}
+	}
 
 	err = cmdRegistry.run(&appState, cmd)
 	if err != nil {
-		log.Fatalf("Command error: %v", err)
+		fatalf("%v", err)
 	}
-}
\ No newline at end of file
+}
@@ -0,0 +1,313 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/josequiceno2000/gator/internal/config"
+	"github.com/josequiceno2000/gator/internal/memstore"
+)
+
+func TestMain(m *testing.M) {
+	// agg logs every fetch; keep test output to failures
+	slog.SetDefault(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	os.Exit(m.Run())
+}
+
+// newTestState returns a state backed by the in-memory store, with a
+// config file of its own so that login and register can write it.
+func newTestState(t *testing.T) *state {
+	t.Helper()
+	t.Setenv(config.EnvDBURL, "")
+	t.Setenv(config.EnvUser, "")
+
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(`{"db_url": "memory:"}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := config.Read(path, "")
+	if err != nil {
+		t.Fatalf("failed to read config: %v", err)
+	}
+	return &state{DB: memstore.New(), CfgPointer: &cfg}
+}
+
+// runCommand runs a gator command line against s, as main would, and
+// returns what it printed.
+func runCommand(t *testing.T, s *state, args ...string) (string, error) {
+	t.Helper()
+
+	registry := commands{}
+	registerCommands(&registry)
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+
+	out := make(chan string)
+	go func() {
+		data, _ := io.ReadAll(r)
+		out <- string(data)
+	}()
+
+	err = registry.run(s, command{Name: args[0], Arguments: args[1:], Context: context.Background()})
+	w.Close()
+	return <-out, err
+}
+
+// mustRun is runCommand for commands expected to succeed.
+func mustRun(t *testing.T, s *state, args ...string) string {
+	t.Helper()
+	out, err := runCommand(t, s, args...)
+	if err != nil {
+		t.Fatalf("gator %s: %v", strings.Join(args, " "), err)
+	}
+	return out
+}
+
+// newFeedServer serves an RSS feed with the given post titles at /feed.xml,
+// one day apart with the first the newest.
+func newFeedServer(t *testing.T, titles ...string) *httptest.Server {
+	t.Helper()
+
+	var items strings.Builder
+	published := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	for i, title := range titles {
+		fmt.Fprintf(&items, "<item><title>%s</title><link>https://example.com/posts/%d</link><description>&lt;p&gt;About %s&lt;/p&gt;</description><pubDate>%s</pubDate></item>",
+			title, i+1, title, published.AddDate(0, 0, -i).Format(time.RFC1123Z))
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/rss+xml")
+		fmt.Fprintf(w, `<?xml version="1.0"?><rss version="2.0"><channel><title>Test</title>%s</channel></rss>`, items.String())
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// aggregate fetches the next feed due, like one tick of `gator agg`.
+func aggregate(t *testing.T, s *state) {
+	t.Helper()
+	if err := scrapeFeeds(context.Background(), s, newAggStatus(time.Minute)); err != nil {
+		t.Fatalf("scrapeFeeds: %v", err)
+	}
+}
+
+func TestRegisterLoginAddFeedFollowBrowse(t *testing.T) {
+	s := newTestState(t)
+	feedURL := newFeedServer(t, "First post", "Second post").URL + "/feed.xml"
+
+	out := mustRun(t, s, "register", "alice")
+	if !strings.Contains(out, "'alice' registered") {
+		t.Errorf("register printed %q", out)
+	}
+	if s.CfgPointer.CurrentUsername != "alice" {
+		t.Errorf("current user after register = %q, want alice", s.CfgPointer.CurrentUsername)
+	}
+	if _, err := runCommand(t, s, "register", "alice"); err == nil {
+		t.Error("registering an existing name succeeded")
+	}
+
+	mustRun(t, s, "addfeed", "Blog", feedURL)
+	if _, err := runCommand(t, s, "addfeed", "Blog again", feedURL); err == nil {
+		t.Error("adding an existing feed URL succeeded")
+	}
+	if out := mustRun(t, s, "following"); strings.TrimSpace(out) != "Blog" {
+		t.Errorf("following after addfeed printed %q, want Blog", out)
+	}
+
+	aggregate(t, s)
+
+	out = mustRun(t, s, "browse", "10")
+	for _, want := range []string{"Title: First post", "Title: Second post", "Feed: Blog", "URL: https://example.com/posts/1"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("browse output is missing %q:\n%s", want, out)
+		}
+	}
+	if strings.Index(out, "First post") > strings.Index(out, "Second post") {
+		t.Errorf("browse isn't newest first:\n%s", out)
+	}
+	if out := mustRun(t, s, "browse", "1"); strings.Count(out, "Title:") != 1 {
+		t.Errorf("browse 1 printed %d posts", strings.Count(out, "Title:"))
+	}
+
+	// A second user sees nothing until following the feed
+	mustRun(t, s, "register", "bob")
+	if out := mustRun(t, s, "browse", "10"); out != "" {
+		t.Errorf("browse without follows printed %q", out)
+	}
+	out = mustRun(t, s, "follow", feedURL)
+	if !strings.Contains(out, "Followed feed: Blog by user: bob") {
+		t.Errorf("follow printed %q", out)
+	}
+	if out := mustRun(t, s, "browse", "10"); strings.Count(out, "Title:") != 2 {
+		t.Errorf("browse after follow printed:\n%s", out)
+	}
+	if _, err := runCommand(t, s, "follow", feedURL); err == nil {
+		t.Error("following a feed twice succeeded")
+	}
+
+	mustRun(t, s, "unfollow", feedURL)
+	if out := mustRun(t, s, "browse", "10"); out != "" {
+		t.Errorf("browse after unfollow printed %q", out)
+	}
+
+	// Logging back in switches whose posts browse shows
+	mustRun(t, s, "login", "alice")
+	if out := mustRun(t, s, "users"); !strings.Contains(out, "* alice (current)") || !strings.Contains(out, "* bob\n") {
+		t.Errorf("users printed %q", out)
+	}
+	if out := mustRun(t, s, "browse", "10"); strings.Count(out, "Title:") != 2 {
+		t.Errorf("browse after login printed:\n%s", out)
+	}
+	if _, err := runCommand(t, s, "login", "carol"); err == nil {
+		t.Error("logging in as a user that doesn't exist succeeded")
+	}
+
+	// Fetching again doesn't duplicate posts
+	aggregate(t, s)
+	if out := mustRun(t, s, "browse", "10"); strings.Count(out, "Title:") != 2 {
+		t.Errorf("browse after a second fetch printed:\n%s", out)
+	}
+}
+
+func TestCommandsRequireLogin(t *testing.T) {
+	s := newTestState(t)
+
+	for _, args := range [][]string{{"browse"}, {"addfeed", "Blog", "https://example.com/feed"}, {"follow", "https://example.com/feed"}} {
+		_, err := runCommand(t, s, args...)
+		if err == nil || !strings.Contains(err.Error(), "not logged in") {
+			t.Errorf("gator %s: got error %v, want not logged in", strings.Join(args, " "), err)
+		}
+	}
+}
+
+func TestBrowseFolder(t *testing.T) {
+	s := newTestState(t)
+	techURL := newFeedServer(t, "Tech post").URL + "/feed.xml"
+	newsURL := newFeedServer(t, "News post").URL + "/feed.xml"
+
+	mustRun(t, s, "register", "alice")
+	mustRun(t, s, "addfeed", "Tech", techURL)
+	mustRun(t, s, "addfeed", "News", newsURL)
+	aggregate(t, s)
+	aggregate(t, s)
+	mustRun(t, s, "folder", "add", "Work", techURL)
+
+	out := mustRun(t, s, "browse", "--folder", "Work", "10")
+	if !strings.Contains(out, "Tech post") || strings.Contains(out, "News post") {
+		t.Errorf("browse --folder Work printed:\n%s", out)
+	}
+	// Both posts link to the same article, but News isn't in the folder
+	if !strings.Contains(out, "Feed: Tech\n") {
+		t.Errorf("browse --folder Work named feeds outside the folder:\n%s", out)
+	}
+}
+
+func TestFolderRejectsBlankNames(t *testing.T) {
+	s := newTestState(t)
+	feedURL := newFeedServer(t, "First post").URL + "/feed.xml"
+
+	mustRun(t, s, "register", "alice")
+	mustRun(t, s, "addfeed", "Blog", feedURL)
+	mustRun(t, s, "folder", "add", "Work", feedURL)
+
+	for _, args := range [][]string{{"folder", "add", "", feedURL}, {"folder", "add", "  ", feedURL}, {"folder", "mv", "Work", "\t"}} {
+		_, err := runCommand(t, s, args...)
+		if err == nil || !strings.Contains(err.Error(), "can't be blank") {
+			t.Errorf("gator %q: got error %v, want blank name", args, err)
+		}
+	}
+	if out := mustRun(t, s, "following", "--tree"); !strings.Contains(out, "Work") {
+		t.Errorf("following --tree after rejected changes printed:\n%s", out)
+	}
+}
+
+func TestReset(t *testing.T) {
+	s := newTestState(t)
+	feedURL := newFeedServer(t, "First post").URL + "/feed.xml"
+
+	mustRun(t, s, "register", "alice")
+	mustRun(t, s, "addfeed", "Blog", feedURL)
+	aggregate(t, s)
+
+	mustRun(t, s, "reset")
+	if out := mustRun(t, s, "users"); out != "" {
+		t.Errorf("users after reset printed %q", out)
+	}
+	if out := mustRun(t, s, "feeds"); out != "" {
+		t.Errorf("feeds after reset printed %q", out)
+	}
+}
+
+func TestPruneMaxPostsKeepsUnreadInsideDayWindow(t *testing.T) {
+	s := newTestState(t)
+	countURL := newFeedServer(t, "Count 1", "Count 2", "Count 3").URL + "/feed.xml"
+	windowURL := newFeedServer(t, "Window 1", "Window 2", "Window 3").URL + "/feed.xml"
+
+	mustRun(t, s, "register", "alice")
+	mustRun(t, s, "addfeed", "Count", countURL)
+	mustRun(t, s, "addfeed", "Window", windowURL)
+	aggregate(t, s)
+	aggregate(t, s)
+
+	// Without a day window the count limit applies to unread posts too
+	mustRun(t, s, "retention", "--days", "0", "--max-posts", "1", countURL)
+	mustRun(t, s, "retention", "--days", "36500", "--max-posts", "1", windowURL)
+
+	out := mustRun(t, s, "prune", "--dry-run")
+	for _, title := range []string{"Count 2", "Count 3"} {
+		if !strings.Contains(out, title) {
+			t.Errorf("prune --dry-run didn't list %q:\n%s", title, out)
+		}
+	}
+	if strings.Contains(out, "Count 1") || strings.Contains(out, "Window") {
+		t.Errorf("prune --dry-run listed a post it must keep:\n%s", out)
+	}
+
+	if out := mustRun(t, s, "prune"); out != "Deleted 2 post(s)\n" {
+		t.Errorf("prune printed %q", out)
+	}
+}
+
+func TestFeedRulesMatchDisplayName(t *testing.T) {
+	s := newTestState(t)
+	blogURL := newFeedServer(t, "Blog post").URL + "/feed.xml"
+	dailyURL := newFeedServer(t, "Daily post").URL + "/feed.xml"
+
+	mustRun(t, s, "register", "alice")
+	mustRun(t, s, "addfeed", "Blog", blogURL)
+	mustRun(t, s, "addfeed", "Daily", dailyURL)
+	mustRun(t, s, "rename-follow", blogURL, "Tech")
+	mustRun(t, s, "rename-follow", dailyURL, "Noise")
+
+	// Rules match the name alice sees, both when fetching and in browse
+	mustRun(t, s, "rules", "add", "--scope", "feed", "--ingest", "mute", "Blog")
+	mustRun(t, s, "rules", "add", "--scope", "feed", "--ingest", "mute", "Noise")
+	aggregate(t, s)
+	aggregate(t, s)
+
+	out := mustRun(t, s, "browse", "10")
+	if !strings.Contains(out, "Blog post") || !strings.Contains(out, "Feed: Tech") {
+		t.Errorf("a rule on the feed's own name muted the renamed feed:\n%s", out)
+	}
+	if strings.Contains(out, "Daily post") {
+		t.Errorf("a rule on the display name didn't mute the feed:\n%s", out)
+	}
+	if out := mustRun(t, s, "browse", "--show-muted", "10"); strings.Contains(out, "Daily post") {
+		t.Errorf("the muted post was stored:\n%s", out)
+	}
+}
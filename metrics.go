@@ -0,0 +1,55 @@
+package main
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/josequiceno2000/gator/internal/metrics"
+)
+
+// aggMetrics is served on /metrics by `agg --listen`.
+var aggMetrics = metrics.NewRegistry()
+
+var (
+	fetchesTotal = aggMetrics.NewCounter("gator_fetches_total",
+		"Feed fetches by HTTP status class (2xx, 4xx, ...), or error when no response arrived.", "status")
+	fetchDuration = aggMetrics.NewHistogram("gator_fetch_duration_seconds",
+		"Time taken to download a feed, including reading the body.", metrics.DefaultBuckets)
+	fetchBytes = aggMetrics.NewCounter("gator_fetch_bytes_total",
+		"Bytes of feed bodies downloaded.")
+	parseErrors = aggMetrics.NewCounter("gator_parse_errors_total",
+		"Downloaded feeds that couldn't be parsed.")
+	postsTotal = aggMetrics.NewCounter("gator_posts_total",
+		"Posts found in fetched feeds by what happened to them: inserted, duplicate, muted, invalid (no usable date) or failed.", "result")
+	feedsGauge = aggMetrics.NewGauge("gator_feeds",
+		"Feeds in the database.")
+	feedsDueGauge = aggMetrics.NewGauge("gator_feeds_due",
+		"Feeds waiting to be fetched: never fetched, or not fetched within one rotation through all feeds.")
+)
+
+// statusClass groups HTTP status codes by their first digit, which keeps
+// the number of series small.
+func statusClass(code int) string {
+	return strconv.Itoa(code/100) + "xx"
+}
+
+// metricsHandler refreshes the queue gauges from the database and serves
+// aggMetrics.
+func metricsHandler(s *state, st *aggStatus) http.Handler {
+	serve := aggMetrics.Handler()
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		st.mu.Lock()
+		interval := st.interval
+		st.mu.Unlock()
+
+		queue, err := feedQueue(r.Context(), s, interval)
+		if err != nil {
+			slog.Error("failed to count due feeds", "err", err)
+		} else {
+			feedsGauge.Set(float64(queue.Total))
+			feedsDueGauge.Set(float64(queue.Due))
+		}
+		serve.ServeHTTP(w, r)
+	})
+}
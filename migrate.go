@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/pressly/goose/v3"
+)
+
+// migrationName names a migration in migrate's output: its file name, or
+// its version for Go migrations, which are registered without a file.
+func migrationName(source *goose.Source) string {
+	if source.Path == "" {
+		return fmt.Sprintf("%03d (%s)", source.Version, source.Type)
+	}
+	return filepath.Base(source.Path)
+}
+
+// formatResult is goose's MigrationResult.String with the migration named
+// by migrationName.
+func formatResult(result *goose.MigrationResult) string {
+	state := "OK"
+	if result.Empty {
+		state = "EMPTY"
+	}
+	return fmt.Sprintf("%-5s %-4s %s (%s)", state, result.Direction, migrationName(result.Source), result.Duration.Round(time.Millisecond))
+}
+
+func newMigrationProvider(db *sql.DB, b backend) (*goose.Provider, error) {
+	return goose.NewProvider(b.dialect, db, b.schema, goose.WithGoMigrations(b.goMigrations...))
+}
+
+// checkSchemaVersion returns an error when the database has migrations
+// that haven't been applied yet, so commands don't fail halfway through on
+// a missing table or column.
+func checkSchemaVersion(db *sql.DB, b backend) error {
+	provider, err := newMigrationProvider(db, b)
+	if err != nil {
+		return fmt.Errorf("failed to load migrations: %w", err)
+	}
+
+	current, target, err := provider.GetVersions(context.Background())
+	if err != nil {
+		return fmt.Errorf("failed to get schema version: %w", err)
+	}
+
+	if current < target {
+		return fmt.Errorf("database schema is at version %d but gator needs version %d; run `gator migrate up`", current, target)
+	}
+	return nil
+}
+
+func handlerMigrate(s *state, cmd command) error {
+	provider, err := newMigrationProvider(s.Conn, s.Backend)
+	if err != nil {
+		return fmt.Errorf("migrate: failed to load migrations: %w", err)
+	}
+
+	ctx := context.Background()
+
+	switch cmd.Arguments[0] {
+	case "up":
+		results, err := provider.Up(ctx)
+		for _, result := range results {
+			fmt.Println(formatResult(result))
+		}
+		if err != nil {
+			return fmt.Errorf("migrate up: %w", err)
+		}
+		if len(results) == 0 {
+			fmt.Println("No migrations to apply")
+		}
+	case "down":
+		result, err := provider.Down(ctx)
+		if err != nil {
+			return fmt.Errorf("migrate down: %w", err)
+		}
+		fmt.Println(formatResult(result))
+	case "redo":
+		down, err := provider.Down(ctx)
+		if err != nil {
+			return fmt.Errorf("migrate redo: %w", err)
+		}
+		fmt.Println(formatResult(down))
+
+		up, err := provider.UpByOne(ctx)
+		if err != nil {
+			return fmt.Errorf("migrate redo: %w", err)
+		}
+		fmt.Println(formatResult(up))
+	case "status":
+		statuses, err := provider.Status(ctx)
+		if err != nil {
+			return fmt.Errorf("migrate status: %w", err)
+		}
+
+		for _, status := range statuses {
+			appliedAt := "Pending"
+			if status.State == goose.StateApplied {
+				appliedAt = status.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%-20s %s\n", appliedAt, migrationName(status.Source))
+		}
+	default:
+		return fmt.Errorf("migrate: unknown subcommand: %s", cmd.Arguments[0])
+	}
+
+	return nil
+}
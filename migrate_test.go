@@ -0,0 +1,38 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pressly/goose/v3"
+)
+
+func TestMigrationName(t *testing.T) {
+	tests := []struct {
+		name   string
+		source goose.Source
+		want   string
+	}{
+		{"sql", goose.Source{Type: goose.TypeSQL, Path: "sql/schema/012_add_canonical_url_to_posts.sql", Version: 12}, "012_add_canonical_url_to_posts.sql"},
+		{"go without file", goose.Source{Type: goose.TypeGo, Version: 14}, "014 (go)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := migrationName(&tt.source); got != tt.want {
+				t.Errorf("migrationName() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatResult(t *testing.T) {
+	result := &goose.MigrationResult{
+		Source:    &goose.Source{Type: goose.TypeGo, Version: 14},
+		Duration:  1234567 * time.Nanosecond,
+		Direction: "up",
+	}
+	if got, want := formatResult(result), "OK    up   014 (go) (1ms)"; got != want {
+		t.Errorf("formatResult() = %q, want %q", got, want)
+	}
+}
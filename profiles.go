@@ -0,0 +1,80 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+
+	"github.com/josequiceno2000/gator/internal/config"
+)
+
+func handlerProfile(s *state, cmd command) error {
+	cfg := s.CfgPointer
+	args := cmd.Arguments[1:]
+
+	switch cmd.Arguments[0] {
+	case "list":
+		names := cfg.Profiles()
+		if len(names) == 0 {
+			fmt.Println("No profiles yet; create one with `gator profile add` or `gator config init`")
+			return nil
+		}
+
+		for _, name := range names {
+			profile, _ := cfg.GetProfile(name)
+
+			marker := " "
+			if name == cfg.Profile {
+				marker = "*"
+			}
+			username := profile.CurrentUsername
+			if username == "" {
+				username = "-"
+			}
+			fmt.Printf("%s %-16s %-50s %s\n", marker, name, redactDBURL(profile.DBURL), username)
+		}
+	case "use":
+		if len(args) < 1 {
+			return errors.New("profile use: profile name is required")
+		}
+
+		if err := cfg.UseProfile(args[0]); err != nil {
+			return fmt.Errorf("profile use: %w", err)
+		}
+		fmt.Printf("Switched to profile: %s\n", args[0])
+	case "add":
+		fs := flag.NewFlagSet("profile add", flag.ContinueOnError)
+		dbURL := fs.String("db-url", "", "database URL for the profile")
+		username := fs.String("user", "", "user to log in as")
+
+		args, err := parseFlags(fs, args)
+		if err != nil {
+			return fmt.Errorf("profile add: %w", err)
+		}
+		if len(args) < 1 || *dbURL == "" {
+			return errors.New("profile add: profile name and --db-url are required")
+		}
+		if _, err := parseDBURL(*dbURL); err != nil {
+			return fmt.Errorf("profile add: invalid database URL: %w", err)
+		}
+
+		err = cfg.AddProfile(args[0], config.Profile{DBURL: *dbURL, CurrentUsername: *username})
+		if err != nil {
+			return fmt.Errorf("profile add: %w", err)
+		}
+		fmt.Printf("Added profile: %s (switch to it with `gator profile use %s`)\n", args[0], args[0])
+	case "rm":
+		if len(args) < 1 {
+			return errors.New("profile rm: profile name is required")
+		}
+
+		if err := cfg.RemoveProfile(args[0]); err != nil {
+			return fmt.Errorf("profile rm: %w", err)
+		}
+		fmt.Printf("Removed profile: %s\n", args[0])
+	default:
+		return fmt.Errorf("profile: unknown subcommand: %s", cmd.Arguments[0])
+	}
+
+	return nil
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/josequiceno2000/gator/internal/database"
+)
+
+// prunePosts deletes posts that fall outside their feed's retention policy
+// and returns how many were removed. Saved posts are always kept, and the
+// post count limit never removes a post that a follower hasn't read yet
+// while it is still inside the day window.
+func prunePosts(ctx context.Context, s *state, dryRun bool) (int, error) {
+	var n int
+	// Selecting and deleting in one transaction keeps posts that are read
+	// or saved in between from being deleted on stale information
+	err := s.withTx(ctx, func(q database.Querier) error {
+		posts, err := q.GetPrunablePosts(ctx, database.GetPrunablePostsParams{
+			DefaultDays:     int32(s.CfgPointer.RetentionDays),
+			DefaultMaxPosts: int32(s.CfgPointer.RetentionMaxPosts),
+			Now:             time.Now().UTC(),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to get prunable posts: %w", err)
+		}
+
+		if dryRun {
+			for _, post := range posts {
+				fmt.Printf("%s  %s: %s\n", post.PublishedAt.Format(time.DateOnly), post.FeedName, post.Title)
+			}
+			n = len(posts)
+			return nil
+		}
+
+		if len(posts) == 0 {
+			return nil
+		}
+
+		ids := make([]uuid.UUID, len(posts))
+		for i, post := range posts {
+			ids[i] = post.ID
+		}
+
+		deleted, err := q.DeletePosts(ctx, ids)
+		if err != nil {
+			return fmt.Errorf("failed to delete posts: %w", err)
+		}
+		n = int(deleted)
+		return nil
+	})
+	return n, err
+}
+
+func handlerPrune(s *state, cmd command) error {
+	dryRun := cmd.boolFlag("dry-run")
+
+	n, err := prunePosts(cmd.Context, s, dryRun)
+	if err != nil {
+		return fmt.Errorf("prune: %w", err)
+	}
+
+	if dryRun {
+		fmt.Printf("%d post(s) would be deleted\n", n)
+	} else {
+		fmt.Printf("Deleted %d post(s)\n", n)
+	}
+	return nil
+}
+
+// pruneOnce prunes posts for agg, logging the outcome. A prune interrupted
+// by ctx is rolled back.
+func pruneOnce(ctx context.Context, s *state) {
+	n, err := prunePosts(ctx, s, false)
+	if err != nil {
+		slog.Error("failed to prune posts", "err", err)
+		return
+	}
+	slog.Info("pruned posts", "deleted", n)
+}
+
+func handlerRetention(s *state, cmd command, user database.User) error {
+	days := cmd.intFlag("days")
+	maxPosts := cmd.intFlag("max-posts")
+	reset := cmd.boolFlag("reset")
+
+	feed, err := s.DB.GetFeedByUrl(context.Background(), cmd.Arguments[0])
+	if err != nil {
+		return fmt.Errorf("retention: failed to get feed: %w", err)
+	}
+
+	if days < 0 && maxPosts < 0 && !reset {
+		fmt.Printf("Keep days: %s\nKeep posts: %s\n",
+			describeRetention(feed.RetentionDays, s.CfgPointer.RetentionDays),
+			describeRetention(feed.RetentionMaxPosts, s.CfgPointer.RetentionMaxPosts))
+		return nil
+	}
+
+	if feed.UserID != user.ID {
+		return errors.New("retention: only the user who added the feed can change its retention")
+	}
+
+	params := database.SetFeedRetentionParams{ID: feed.ID}
+	if !reset {
+		params.RetentionDays = feed.RetentionDays
+		params.RetentionMaxPosts = feed.RetentionMaxPosts
+		if days >= 0 {
+			params.RetentionDays = sql.NullInt32{Int32: int32(days), Valid: true}
+		}
+		if maxPosts >= 0 {
+			params.RetentionMaxPosts = sql.NullInt32{Int32: int32(maxPosts), Valid: true}
+		}
+	}
+
+	err = s.DB.SetFeedRetention(context.Background(), params)
+	if err != nil {
+		return fmt.Errorf("retention: failed to update feed: %w", err)
+	}
+
+	fmt.Printf("Updated retention for feed: %s\n", feed.Name)
+	return nil
+}
+
+func describeRetention(override sql.NullInt32, global int) string {
+	value, source := global, "global default"
+	if override.Valid {
+		value, source = int(override.Int32), "feed override"
+	}
+	if value == 0 {
+		return fmt.Sprintf("no limit (%s)", source)
+	}
+	return fmt.Sprintf("%d (%s)", value, source)
+}
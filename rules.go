@@ -0,0 +1,254 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/josequiceno2000/gator/internal/database"
+)
+
+const (
+	ruleActionMute      = "mute"
+	ruleActionHighlight = "highlight"
+)
+
+var ruleScopes = []string{"title", "description", "author", "feed"}
+
+// postFields holds the parts of a post that rules can match against.
+type postFields struct {
+	Title       string
+	Description string
+	Author      string
+	FeedName    string
+}
+
+type compiledRule struct {
+	database.Rule
+	re *regexp.Regexp
+}
+
+func compileRules(rules []database.Rule) ([]compiledRule, error) {
+	compiled := make([]compiledRule, 0, len(rules))
+	for _, rule := range rules {
+		cr := compiledRule{Rule: rule}
+		if rule.IsRegex {
+			re, err := regexp.Compile(rule.Pattern)
+			if err != nil {
+				return nil, fmt.Errorf("rule %s: invalid regex: %w", rule.ID, err)
+			}
+			cr.re = re
+		}
+		compiled = append(compiled, cr)
+	}
+	return compiled, nil
+}
+
+func (r compiledRule) matches(p postFields) bool {
+	var field string
+	switch r.Scope {
+	case "title":
+		field = p.Title
+	case "description":
+		field = p.Description
+	case "author":
+		field = p.Author
+	case "feed":
+		field = p.FeedName
+	}
+
+	if r.re != nil {
+		return r.re.MatchString(field)
+	}
+	return strings.Contains(strings.ToLower(field), strings.ToLower(r.Pattern))
+}
+
+// classifyPost reports whether any of the rules mute or highlight the post.
+func classifyPost(rules []compiledRule, p postFields) (muted, highlighted bool) {
+	for _, rule := range rules {
+		if !rule.matches(p) {
+			continue
+		}
+		switch rule.Action {
+		case ruleActionMute:
+			muted = true
+		case ruleActionHighlight:
+			highlighted = true
+		}
+	}
+	return muted, highlighted
+}
+
+// ingestMuter decides whether a freshly fetched item should be dropped
+// before it is stored. Posts are shared between followers, so an item is
+// only dropped when every follower of the feed mutes it at ingest. Each
+// follower's "feed" rules match the name they gave the feed, as in browse.
+type ingestMuter struct {
+	followers int64
+	rules     map[uuid.UUID][]compiledRule
+	feedNames map[uuid.UUID]string
+}
+
+func loadIngestMuter(s *state, feedID uuid.UUID) (*ingestMuter, error) {
+	rows, err := s.DB.GetIngestMuteRulesForFeed(context.Background(), feedID)
+	if err != nil {
+		return nil, err
+	}
+
+	followers, err := s.DB.CountFeedFollowers(context.Background(), feedID)
+	if err != nil {
+		return nil, err
+	}
+
+	rules := make([]database.Rule, 0, len(rows))
+	feedNames := make(map[uuid.UUID]string)
+	for _, row := range rows {
+		rules = append(rules, database.Rule{
+			ID:            row.ID,
+			CreatedAt:     row.CreatedAt,
+			UpdatedAt:     row.UpdatedAt,
+			UserID:        row.UserID,
+			Action:        row.Action,
+			Scope:         row.Scope,
+			Pattern:       row.Pattern,
+			IsRegex:       row.IsRegex,
+			ApplyAtIngest: row.ApplyAtIngest,
+		})
+		feedNames[row.UserID] = row.FeedName
+	}
+
+	compiled, err := compileRules(rules)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &ingestMuter{followers: followers, rules: make(map[uuid.UUID][]compiledRule), feedNames: feedNames}
+	for _, rule := range compiled {
+		m.rules[rule.UserID] = append(m.rules[rule.UserID], rule)
+	}
+	return m, nil
+}
+
+// muted reports whether every follower mutes p, matching "feed" rules
+// against each follower's own name for the feed.
+func (m *ingestMuter) muted(p postFields) bool {
+	if m == nil || m.followers == 0 || int64(len(m.rules)) < m.followers {
+		return false
+	}
+
+	for userID, rules := range m.rules {
+		p.FeedName = m.feedNames[userID]
+		if muted, _ := classifyPost(rules, p); !muted {
+			return false
+		}
+	}
+	return true
+}
+
+func handlerRules(s *state, cmd command, user database.User) error {
+	args := cmd.Arguments[1:]
+
+	switch cmd.Arguments[0] {
+	case "add":
+		return handlerRulesAdd(s, args, user)
+	case "list":
+		rules, err := s.DB.GetRulesForUser(context.Background(), user.ID)
+		if err != nil {
+			return fmt.Errorf("rules list: failed to get rules: %w", err)
+		}
+
+		for _, rule := range rules {
+			kind := "keyword"
+			if rule.IsRegex {
+				kind = "regex"
+			}
+			ingest := ""
+			if rule.ApplyAtIngest {
+				ingest = " (ingest)"
+			}
+			fmt.Printf("%s  %s %s %s: %q%s\n", rule.ID, rule.Action, rule.Scope, kind, rule.Pattern, ingest)
+		}
+	case "rm":
+		if len(args) < 1 {
+			return errors.New("rules rm: id argument is required")
+		}
+
+		id, err := uuid.Parse(args[0])
+		if err != nil {
+			return fmt.Errorf("rules rm: invalid id: %w", err)
+		}
+
+		n, err := s.DB.DeleteRule(context.Background(), database.DeleteRuleParams{
+			ID:     id,
+			UserID: user.ID,
+		})
+		if err != nil {
+			return fmt.Errorf("rules rm: failed to delete rule: %w", err)
+		}
+		if n == 0 {
+			return fmt.Errorf("rules rm: rule does not exist: %s", id)
+		}
+
+		fmt.Printf("Deleted rule: %s\n", id)
+	default:
+		return fmt.Errorf("rules: unknown subcommand: %s", cmd.Arguments[0])
+	}
+
+	return nil
+}
+
+func handlerRulesAdd(s *state, args []string, user database.User) error {
+	fs := flag.NewFlagSet("rules add", flag.ContinueOnError)
+	scope := fs.String("scope", "title", "field to match: title, description, author or feed")
+	isRegex := fs.Bool("regex", false, "treat the pattern as a regular expression")
+	ingest := fs.Bool("ingest", false, "also drop matching posts when they are fetched (mute only)")
+
+	args, err := parseFlags(fs, args)
+	if err != nil {
+		return fmt.Errorf("rules add: %w", err)
+	}
+	if len(args) < 2 {
+		return errors.New("rules add: action (mute or highlight) and pattern arguments are required")
+	}
+
+	action, pattern := args[0], args[1]
+
+	if action != ruleActionMute && action != ruleActionHighlight {
+		return fmt.Errorf("rules add: unknown action: %s", action)
+	}
+	if !slices.Contains(ruleScopes, *scope) {
+		return fmt.Errorf("rules add: unknown scope: %s", *scope)
+	}
+	if *ingest && action != ruleActionMute {
+		return errors.New("rules add: --ingest only applies to mute rules")
+	}
+	if *isRegex {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return fmt.Errorf("rules add: invalid regex: %w", err)
+		}
+	}
+
+	rule, err := s.DB.CreateRule(context.Background(), database.CreateRuleParams{
+		ID:            uuid.New(),
+		CreatedAt:     time.Now().UTC(),
+		UpdatedAt:     time.Now().UTC(),
+		UserID:        user.ID,
+		Action:        action,
+		Scope:         *scope,
+		Pattern:       pattern,
+		IsRegex:       *isRegex,
+		ApplyAtIngest: *ingest,
+	})
+	if err != nil {
+		return fmt.Errorf("rules add: failed to create rule: %w", err)
+	}
+
+	fmt.Printf("Created rule: %s\n", rule.ID)
+	return nil
+}
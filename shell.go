@@ -0,0 +1,421 @@
+package main
+
+import (
+	"bufio"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/josequiceno2000/gator/internal/config"
+	"golang.org/x/term"
+)
+
+const (
+	historyFileName = ".gator_history"
+	// How many lines of history are kept in the history file
+	historySize = 1000
+)
+
+var errShellExit = errors.New("exit")
+
+// shell runs command lines against a single state, so the config is read
+// and the database opened once for the whole session.
+type shell struct {
+	c *commands
+	s *state
+	// dbURL is the database s is connected to, which changes when a
+	// command switches profiles or rewrites the config
+	dbURL string
+	// mainConn is the connection main opened and closes
+	mainConn *sql.DB
+}
+
+func handlerShell(c *commands) func(*state, command) error {
+	return func(s *state, cmd command) error {
+		sh := &shell{c: c, s: s, dbURL: s.CfgPointer.DBURL, mainConn: s.Conn}
+		defer sh.closeConn()
+
+		keepGoing := cmd.boolFlag("keep-going")
+
+		if len(cmd.Arguments) > 0 {
+			f, err := os.Open(cmd.Arguments[0])
+			if err != nil {
+				return fmt.Errorf("shell: %w", err)
+			}
+			defer f.Close()
+			return sh.runScript(f, keepGoing)
+		}
+
+		if !term.IsTerminal(int(os.Stdin.Fd())) {
+			return sh.runScript(os.Stdin, keepGoing)
+		}
+		return sh.runInteractive()
+	}
+}
+
+// runScript runs one command per line of r. Blank lines and lines starting
+// with # are skipped. It stops at the first failing command unless
+// keepGoing is set.
+func (sh *shell) runScript(r io.Reader, keepGoing bool) error {
+	scanner := bufio.NewScanner(r)
+	failed := 0
+	for n := 1; scanner.Scan(); n++ {
+		err := sh.exec(scanner.Text())
+		if errors.Is(err, errShellExit) {
+			break
+		}
+		if err != nil {
+			if !keepGoing {
+				return fmt.Errorf("shell: line %d: %w", n, err)
+			}
+			fmt.Fprintf(os.Stderr, "line %d: %v\n", n, err)
+			failed++
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("shell: failed to read commands: %w", err)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("shell: %d command(s) failed", failed)
+	}
+	return nil
+}
+
+func (sh *shell) runInteractive() error {
+	fd := int(os.Stdin.Fd())
+
+	t := term.NewTerminal(struct {
+		io.Reader
+		io.Writer
+	}{os.Stdin, os.Stdout}, "gator> ")
+	t.AutoCompleteCallback = sh.complete
+
+	history, err := loadHistory()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: history won't be saved: %v\n", err)
+	}
+	t.History = history
+
+	fmt.Println("gator shell; type `help` for a list of commands and `exit` to leave")
+
+	for {
+		// The terminal is only raw while a line is edited, so that commands
+		// print and read as usual
+		oldState, err := term.MakeRaw(fd)
+		if err != nil {
+			return fmt.Errorf("shell: %w", err)
+		}
+		if width, height, err := term.GetSize(fd); err == nil && width > 0 {
+			t.SetSize(width, height)
+		}
+		line, err := t.ReadLine()
+		term.Restore(fd, oldState)
+
+		if errors.Is(err, io.EOF) {
+			fmt.Println()
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("shell: %w", err)
+		}
+
+		err = sh.exec(line)
+		if errors.Is(err, errShellExit) {
+			return nil
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		}
+	}
+}
+
+// exec runs a single command line.
+func (sh *shell) exec(line string) error {
+	words, err := splitWords(line)
+	if err != nil {
+		return err
+	}
+	if len(words) == 0 || strings.HasPrefix(words[0], "#") {
+		return nil
+	}
+
+	switch words[0] {
+	case "exit", "quit":
+		return errShellExit
+	case "shell":
+		return errors.New("shell: already in a shell")
+	}
+
+	spec, err := sh.c.lookup(words[0])
+	if err != nil {
+		return err
+	}
+
+	cmd := command{Name: words[0], Arguments: words[1:]}
+	if !spec.NoDatabase && !wantsHelp(cmd.Arguments) {
+		if sh.s.DB == nil {
+			return fmt.Errorf("%s: no database is configured; run `config init` or set %s", cmd.Name, config.EnvDBURL)
+		}
+		if !spec.AnySchema {
+			if err := checkSchemaVersion(sh.s.Conn, sh.s.Backend); err != nil {
+				return err
+			}
+		}
+	}
+
+	err = sh.c.run(sh.s, cmd)
+	sh.reconnect()
+	return err
+}
+
+// reconnect opens the database named by the config if a command changed
+// it, such as `profile use` or `config init`.
+func (sh *shell) reconnect() {
+	dbURL := sh.s.CfgPointer.DBURL
+	if dbURL == sh.dbURL {
+		return
+	}
+
+	db, b, err := openDatabase(dbURL)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to open %s: %v\n", redactDBURL(dbURL), err)
+		return
+	}
+
+	sh.closeConn()
+	sh.s.DB = b.queries(db)
+	sh.s.Conn = db
+	sh.s.Backend = b
+	sh.dbURL = dbURL
+}
+
+// closeConn closes the current connection if the shell opened it.
+func (sh *shell) closeConn() {
+	if sh.s.Conn != nil && sh.s.Conn != sh.mainConn {
+		sh.s.Conn.Close()
+	}
+}
+
+// complete handles tab completion of the word before the cursor, filling in
+// as much as all candidates have in common.
+func (sh *shell) complete(line string, pos int, key rune) (string, int, bool) {
+	if key != '\t' {
+		return "", 0, false
+	}
+
+	head := line[:pos]
+	words, err := splitWords(head)
+	if err != nil {
+		return "", 0, false
+	}
+	if head == "" || unicode.IsSpace(rune(head[len(head)-1])) {
+		words = append(words, "")
+	}
+
+	cur := words[len(words)-1]
+	// Words with quotes or escapes in them aren't completed
+	if !strings.HasSuffix(head, cur) {
+		return "", 0, false
+	}
+
+	candidates := completeWords(sh.c, sh.s, words)
+	if len(words) == 1 {
+		for _, builtin := range []string{"exit", "quit"} {
+			if strings.HasPrefix(builtin, cur) {
+				candidates = append(candidates, builtin)
+			}
+		}
+	}
+	if len(candidates) == 0 {
+		return "", 0, false
+	}
+
+	completion := commonPrefix(candidates)
+	if len(candidates) == 1 {
+		if strings.ContainsFunc(completion, unicode.IsSpace) {
+			completion = "'" + completion + "'"
+		}
+		completion += " "
+	}
+	if completion == cur {
+		return "", 0, false
+	}
+
+	newHead := head[:len(head)-len(cur)] + completion
+	return newHead + line[pos:], len(newHead), true
+}
+
+func commonPrefix(words []string) string {
+	prefix := words[0]
+	for _, word := range words[1:] {
+		for !strings.HasPrefix(word, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+	return prefix
+}
+
+// splitWords splits a command line into words at whitespace. Single quotes
+// keep everything up to the closing quote, double quotes do the same but
+// allow \" and \\ inside, and a backslash outside quotes escapes the next
+// character.
+func splitWords(line string) ([]string, error) {
+	var words []string
+	var word strings.Builder
+	inWord := false
+	var quote rune
+	escaped := false
+
+	for _, r := range line {
+		switch {
+		case escaped:
+			word.WriteRune(r)
+			escaped = false
+		case quote == '\'':
+			if r == '\'' {
+				quote = 0
+			} else {
+				word.WriteRune(r)
+			}
+		case quote == '"':
+			switch r {
+			case '"':
+				quote = 0
+			case '\\':
+				escaped = true
+			default:
+				word.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote = r
+			inWord = true
+		case r == '\\':
+			escaped = true
+			inWord = true
+		case unicode.IsSpace(r):
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated %c quote", quote)
+	}
+	if escaped {
+		return nil, errors.New("line ends with a backslash")
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, nil
+}
+
+// fileHistory is the shell's line history, saved to ~/.gator_history so
+// that it carries over between sessions.
+type fileHistory struct {
+	entries []string
+	path    string
+}
+
+func loadHistory() (*fileHistory, error) {
+	h := &fileHistory{}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return h, err
+	}
+	h.path = filepath.Join(home, historyFileName)
+
+	data, err := os.ReadFile(h.path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return h, err
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if line != "" {
+			h.entries = append(h.entries, line)
+		}
+	}
+	if len(h.entries) > historySize {
+		h.entries = h.entries[len(h.entries)-historySize:]
+	}
+	return h, nil
+}
+
+func (h *fileHistory) Add(entry string) {
+	if strings.TrimSpace(entry) == "" {
+		return
+	}
+	if len(h.entries) > 0 && h.entries[len(h.entries)-1] == entry {
+		return
+	}
+
+	h.entries = append(h.entries, entry)
+	if len(h.entries) > historySize {
+		h.entries = h.entries[len(h.entries)-historySize:]
+	}
+	h.save()
+}
+
+func (h *fileHistory) Len() int {
+	return len(h.entries)
+}
+
+func (h *fileHistory) At(idx int) string {
+	return h.entries[len(h.entries)-1-idx]
+}
+
+// save rewrites the history file, with credentials redacted. Failing to
+// save history isn't worth interrupting the session for.
+func (h *fileHistory) save() {
+	if h.path == "" {
+		return
+	}
+
+	lines := make([]string, len(h.entries))
+	for i, entry := range h.entries {
+		lines[i] = redactHistory(entry)
+	}
+	if err := os.WriteFile(h.path, []byte(strings.Join(lines, "\n")+"\n"), 0600); err != nil {
+		return
+	}
+	// WriteFile only sets the mode of files it creates
+	os.Chmod(h.path, 0600)
+}
+
+// historySecrets match the credentials a command line can contain: the
+// password in a URL such as a Postgres db_url, and password= in a key=value
+// connection string.
+var historySecrets = []*regexp.Regexp{
+	regexp.MustCompile(`://[^/\s@:]*:([^/\s@]+)@`),
+	regexp.MustCompile(`(?i)\bpassword\s*=\s*('[^']*'|[^\s'"]+)`),
+}
+
+// redactHistory replaces the credentials in a command line with "xxxxx",
+// as url.URL.Redacted does, so they aren't written to the history file.
+func redactHistory(line string) string {
+	for _, re := range historySecrets {
+		var b strings.Builder
+		last := 0
+		for _, m := range re.FindAllStringSubmatchIndex(line, -1) {
+			b.WriteString(line[last:m[2]])
+			b.WriteString("xxxxx")
+			last = m[3]
+		}
+		b.WriteString(line[last:])
+		line = b.String()
+	}
+	return line
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/josequiceno2000/gator/internal/database"
+	"github.com/josequiceno2000/gator/internal/render"
+	"golang.org/x/term"
+)
+
+const defaultRenderWidth = 80
+
+func handlerShow(s *state, cmd command, user database.User) error {
+	id, err := uuid.Parse(cmd.Arguments[0])
+	if err != nil {
+		return fmt.Errorf("show: invalid post id: %w", err)
+	}
+
+	post, err := s.DB.GetPostForUser(context.Background(), database.GetPostForUserParams{
+		UserID: user.ID,
+		ID:     id,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("show: post not found in followed feeds: %s", id)
+		}
+		return fmt.Errorf("show: failed to get post: %w", err)
+	}
+
+	fmt.Printf("Title: %s\nFeed: %s\n", post.Title, post.FeedName)
+	if post.Author.Valid {
+		fmt.Printf("Author: %s\n", post.Author.String)
+	}
+	fmt.Printf("URL: %s\nPublished: %s\n", post.Url, post.PublishedAt)
+
+	if post.Description.Valid {
+		fmt.Printf("\n%s\n", render.Text(post.Description.String, terminalWidth()))
+	}
+
+	if !post.ReadAt.Valid {
+		now := time.Now().UTC()
+		err = s.DB.SetPostRead(context.Background(), database.SetPostReadParams{
+			ID:        uuid.New(),
+			CreatedAt: now,
+			UpdatedAt: now,
+			UserID:    user.ID,
+			PostID:    post.ID,
+			ReadAt:    sql.NullTime{Time: now, Valid: true},
+		})
+		if err != nil {
+			return fmt.Errorf("show: failed to mark post read: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// terminalWidth returns the width of stdout when it is a terminal, or a
+// fixed default when output is redirected.
+func terminalWidth() int {
+	width, _, err := term.GetSize(int(os.Stdout.Fd()))
+	if err != nil || width <= 0 {
+		return defaultRenderWidth
+	}
+	return width
+}
@@ -0,0 +1,19 @@
+-- name: CreateAPIToken :one
+INSERT INTO api_tokens (id, created_at, updated_at, user_id, name, token_hash)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING *;
+
+-- name: GetAPITokensForUser :many
+SELECT * FROM api_tokens
+WHERE user_id = $1
+ORDER BY name;
+
+-- name: GetUserByAPIToken :one
+SELECT users.*
+FROM users
+JOIN api_tokens ON api_tokens.user_id = users.id
+WHERE api_tokens.token_hash = $1;
+
+-- name: DeleteAPIToken :execrows
+DELETE FROM api_tokens
+WHERE user_id = $1 AND name = $2;
@@ -10,11 +10,37 @@ SELECT * FROM feeds WHERE url = $1;
 
 -- name: GetFeedFollowsForUser :many
 SELECT feed_follows.*,
-    (SELECT name FROM feeds WHERE feeds.id = feed_follows.feed_id) as feed_name,
-    (SELECT name FROM users WHERE users.id = feed_follows.user_id) as user_name
+    COALESCE(feed_follows.display_name, (SELECT name FROM feeds WHERE feeds.id = feed_follows.feed_id)) as feed_name,
+    (SELECT name FROM users WHERE users.id = feed_follows.user_id) as user_name,
+    (SELECT url FROM feeds WHERE feeds.id = feed_follows.feed_id) as feed_url
 FROM feed_follows
 WHERE feed_follows.user_id = $1;
 
 -- name: DeleteFeedFollow :exec
 DELETE FROM feed_follows
-WHERE feed_follows.user_id = $1 AND feed_id = (SELECT id FROM feeds WHERE url = $2);
\ No newline at end of file
+WHERE feed_follows.user_id = $1 AND feed_id = (SELECT id FROM feeds WHERE url = $2);
+
+-- name: SetFeedFollowFolder :execrows
+UPDATE feed_follows
+SET folder = $3, updated_at = NOW()
+WHERE user_id = $1 AND feed_id = (SELECT id FROM feeds WHERE url = $2);
+
+-- name: ClearFolder :execrows
+UPDATE feed_follows
+SET folder = NULL, updated_at = NOW()
+WHERE user_id = sqlc.arg(user_id)
+    AND folder = sqlc.arg(folder)::text
+    AND (sqlc.narg(url)::text IS NULL OR feed_id = (SELECT id FROM feeds WHERE url = sqlc.narg(url)));
+
+-- name: RenameFolder :execrows
+UPDATE feed_follows
+SET folder = sqlc.arg(new_folder)::text, updated_at = NOW()
+WHERE user_id = sqlc.arg(user_id) AND folder = sqlc.arg(old_folder)::text;
+
+-- name: SetFeedFollowDisplayName :execrows
+UPDATE feed_follows
+SET display_name = $3, updated_at = NOW()
+WHERE user_id = $1 AND feed_id = (SELECT id FROM feeds WHERE url = $2);
+
+-- name: CountFeedFollowers :one
+SELECT COUNT(*) FROM feed_follows WHERE feed_id = $1;
@@ -15,6 +15,9 @@ FROM
     feeds
     JOIN users ON feeds.user_id = users.id;
 
+-- name: GetFeedFetchTimes :many
+SELECT last_fetched_at FROM feeds;
+
 -- name: MarkFeedFetched :exec
 UPDATE feeds
 SET last_fetched_at = NOW(), updated_at = NOW()
@@ -24,4 +27,9 @@ WHERE id = $1;
 SELECT *
 FROM feeds
 ORDER BY last_fetched_at ASC NULLS FIRST
-LIMIT 1;
\ No newline at end of file
+LIMIT 1;
+
+-- name: SetFeedRetention :exec
+UPDATE feeds
+SET retention_days = $2, retention_max_posts = $3, updated_at = NOW()
+WHERE id = $1;
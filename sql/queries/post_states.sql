@@ -0,0 +1,11 @@
+-- name: SetPostRead :exec
+INSERT INTO post_states (id, created_at, updated_at, user_id, post_id, read_at)
+VALUES ($1, $2, $3, $4, $5, $6)
+ON CONFLICT (user_id, post_id) DO UPDATE
+SET read_at = EXCLUDED.read_at, updated_at = EXCLUDED.updated_at;
+
+-- name: SetPostSaved :exec
+INSERT INTO post_states (id, created_at, updated_at, user_id, post_id, saved_at)
+VALUES ($1, $2, $3, $4, $5, $6)
+ON CONFLICT (user_id, post_id) DO UPDATE
+SET saved_at = EXCLUDED.saved_at, updated_at = EXCLUDED.updated_at;
@@ -1,14 +1,107 @@
 -- name: CreatePost :one
-INSERT INTO posts (id, created_at, updated_at, title, url, description, published_at, feed_id)
-VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+INSERT INTO posts (id, created_at, updated_at, title, url, description, published_at, feed_id, author, canonical_url)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
 RETURNING *;
 
 
 -- name: GetPostsForUser :many
-SELECT posts.*
+SELECT posts.*,
+    COALESCE(feed_follows.display_name, feeds.name) AS feed_name,
+    post_states.read_at,
+    post_states.saved_at
 FROM posts
 JOIN feeds ON posts.feed_id = feeds.id
 JOIN feed_follows ON feeds.id = feed_follows.feed_id
-WHERE feed_follows.user_id = $1
-ORDER BY posts.published_at DESC
-LIMIT $2;
\ No newline at end of file
+LEFT JOIN post_states ON post_states.post_id = posts.id AND post_states.user_id = feed_follows.user_id
+WHERE feed_follows.user_id = sqlc.arg(user_id)
+    AND (sqlc.narg(folder)::text IS NULL OR feed_follows.folder = sqlc.narg(folder))
+    AND (sqlc.narg(feed_id)::uuid IS NULL OR posts.feed_id = sqlc.narg(feed_id))
+    AND (NOT sqlc.arg(unread_only)::boolean OR post_states.read_at IS NULL)
+    AND (NOT sqlc.arg(saved_only)::boolean OR post_states.saved_at IS NOT NULL)
+    AND (sqlc.narg(published_after)::timestamp IS NULL OR posts.published_at > sqlc.narg(published_after))
+ORDER BY CASE WHEN sqlc.arg(oldest_first)::boolean THEN posts.published_at END ASC, posts.published_at DESC
+LIMIT sqlc.arg('limit') OFFSET sqlc.arg('offset');
+
+-- name: GetPostForUser :one
+SELECT posts.*,
+    COALESCE(feed_follows.display_name, feeds.name) AS feed_name,
+    post_states.read_at,
+    post_states.saved_at
+FROM posts
+JOIN feeds ON posts.feed_id = feeds.id
+JOIN feed_follows ON feeds.id = feed_follows.feed_id
+LEFT JOIN post_states ON post_states.post_id = posts.id AND post_states.user_id = feed_follows.user_id
+WHERE feed_follows.user_id = $1 AND posts.id = $2;
+
+-- name: GetPostIDInRange :one
+SELECT id FROM posts
+WHERE id BETWEEN sqlc.arg(id_from) AND sqlc.arg(id_to)
+ORDER BY id
+LIMIT 1;
+
+-- name: GetUnreadCountsForUser :many
+SELECT posts.feed_id, COUNT(*) AS unread
+FROM posts
+JOIN feed_follows ON posts.feed_id = feed_follows.feed_id
+LEFT JOIN post_states ON post_states.post_id = posts.id AND post_states.user_id = feed_follows.user_id
+WHERE feed_follows.user_id = $1 AND post_states.read_at IS NULL
+GROUP BY posts.feed_id;
+
+-- name: GetPrunablePosts :many
+WITH ranked AS (
+    SELECT
+        posts.id,
+        posts.title,
+        posts.published_at,
+        posts.feed_id,
+        feeds.name AS feed_name,
+        ROW_NUMBER() OVER (PARTITION BY posts.feed_id ORDER BY posts.published_at DESC) AS position,
+        COALESCE(feeds.retention_days, sqlc.arg(default_days)::int) AS keep_days,
+        COALESCE(feeds.retention_max_posts, sqlc.arg(default_max_posts)::int) AS keep_posts
+    FROM posts
+    JOIN feeds ON posts.feed_id = feeds.id
+)
+SELECT ranked.id, ranked.title, ranked.feed_name, ranked.published_at
+FROM ranked
+WHERE NOT EXISTS (
+        SELECT 1 FROM post_states
+        WHERE post_states.post_id = ranked.id AND post_states.saved_at IS NOT NULL
+    )
+    AND (
+        (ranked.keep_days > 0 AND ranked.published_at < sqlc.arg(now)::timestamp - make_interval(days => ranked.keep_days))
+        OR (
+            ranked.keep_posts > 0 AND ranked.position > ranked.keep_posts
+            AND (
+                ranked.keep_days = 0
+                OR NOT EXISTS (
+                    SELECT 1 FROM feed_follows
+                    WHERE feed_follows.feed_id = ranked.feed_id
+                        AND NOT EXISTS (
+                            SELECT 1 FROM post_states
+                            WHERE post_states.post_id = ranked.id
+                                AND post_states.user_id = feed_follows.user_id
+                                AND post_states.read_at IS NOT NULL
+                        )
+                )
+            )
+        )
+    )
+ORDER BY ranked.feed_name, ranked.published_at;
+
+-- name: DeletePosts :execrows
+DELETE FROM posts
+WHERE id = ANY(sqlc.arg(ids)::uuid[])
+    AND NOT EXISTS (
+        SELECT 1 FROM post_states
+        WHERE post_states.post_id = posts.id AND post_states.saved_at IS NOT NULL
+    );
+
+-- name: GetFeedNamesForCanonicalURLs :many
+SELECT DISTINCT posts.canonical_url, COALESCE(feed_follows.display_name, feeds.name) AS feed_name
+FROM posts
+JOIN feeds ON posts.feed_id = feeds.id
+JOIN feed_follows ON feeds.id = feed_follows.feed_id
+WHERE feed_follows.user_id = sqlc.arg(user_id)
+    AND (sqlc.narg(folder)::text IS NULL OR feed_follows.folder = sqlc.narg(folder))
+    AND posts.canonical_url = ANY(sqlc.arg(canonical_urls)::text[])
+ORDER BY posts.canonical_url, feed_name;
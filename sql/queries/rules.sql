@@ -0,0 +1,23 @@
+-- name: CreateRule :one
+INSERT INTO rules (id, created_at, updated_at, user_id, action, scope, pattern, is_regex, apply_at_ingest)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+RETURNING *;
+
+-- name: GetRulesForUser :many
+SELECT *
+FROM rules
+WHERE user_id = $1
+ORDER BY created_at ASC;
+
+-- name: DeleteRule :execrows
+DELETE FROM rules
+WHERE id = $1 AND user_id = $2;
+
+-- name: GetIngestMuteRulesForFeed :many
+SELECT rules.*, COALESCE(feed_follows.display_name, feeds.name) AS feed_name
+FROM rules
+JOIN feed_follows ON rules.user_id = feed_follows.user_id
+JOIN feeds ON feeds.id = feed_follows.feed_id
+WHERE feed_follows.feed_id = $1
+    AND rules.action = 'mute'
+    AND rules.apply_at_ingest;
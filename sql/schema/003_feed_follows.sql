@@ -9,4 +9,4 @@ CREATE TABLE feed_follows (
 );
 
 -- +goose Down
-DROP TABLE feed-follows;
\ No newline at end of file
+DROP TABLE feed_follows;
\ No newline at end of file
@@ -0,0 +1,15 @@
+-- +goose Up
+CREATE TABLE rules (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    action TEXT NOT NULL CHECK (action IN ('mute', 'highlight')),
+    scope TEXT NOT NULL CHECK (scope IN ('title', 'description', 'author', 'feed')),
+    pattern TEXT NOT NULL,
+    is_regex BOOLEAN NOT NULL DEFAULT FALSE,
+    apply_at_ingest BOOLEAN NOT NULL DEFAULT FALSE
+);
+
+-- +goose Down
+DROP TABLE rules;
@@ -0,0 +1,14 @@
+-- +goose Up
+CREATE TABLE post_states (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    post_id UUID NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+    read_at TIMESTAMP NULL,
+    saved_at TIMESTAMP NULL,
+    UNIQUE(user_id, post_id)
+);
+
+-- +goose Down
+DROP TABLE post_states;
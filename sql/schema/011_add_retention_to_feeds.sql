@@ -0,0 +1,7 @@
+-- +goose Up
+ALTER TABLE feeds ADD COLUMN retention_days INTEGER NULL;
+ALTER TABLE feeds ADD COLUMN retention_max_posts INTEGER NULL;
+
+-- +goose Down
+ALTER TABLE feeds DROP COLUMN retention_max_posts;
+ALTER TABLE feeds DROP COLUMN retention_days;
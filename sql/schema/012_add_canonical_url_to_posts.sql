@@ -0,0 +1,18 @@
+-- +goose Up
+ALTER TABLE posts ADD COLUMN canonical_url TEXT NULL;
+-- A placeholder until 014 canonicalizes it in Go, with urlnorm
+UPDATE posts SET canonical_url = url;
+ALTER TABLE posts ALTER COLUMN canonical_url SET NOT NULL;
+CREATE INDEX posts_canonical_url_idx ON posts (canonical_url);
+
+-- The same article may now be stored once per feed that publishes it
+ALTER TABLE posts DROP CONSTRAINT posts_url_key;
+ALTER TABLE posts ADD CONSTRAINT posts_feed_id_url_key UNIQUE (feed_id, url);
+
+-- +goose Down
+DELETE FROM posts a USING posts b
+WHERE a.url = b.url AND (a.created_at, a.id) > (b.created_at, b.id);
+ALTER TABLE posts DROP CONSTRAINT posts_feed_id_url_key;
+ALTER TABLE posts ADD CONSTRAINT posts_url_key UNIQUE (url);
+DROP INDEX posts_canonical_url_idx;
+ALTER TABLE posts DROP COLUMN canonical_url;
@@ -0,0 +1,13 @@
+-- +goose Up
+CREATE TABLE api_tokens (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    token_hash TEXT UNIQUE NOT NULL,
+    UNIQUE(user_id, name)
+);
+
+-- +goose Down
+DROP TABLE api_tokens;
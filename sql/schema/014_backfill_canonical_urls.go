@@ -0,0 +1,53 @@
+package schema
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/josequiceno2000/gator/internal/urlnorm"
+	"github.com/pressly/goose/v3"
+)
+
+// GoMigrations are the migrations written in Go, for changes SQL can't
+// express. They are numbered along with the SQL files in this directory.
+var GoMigrations = []*goose.Migration{
+	// 012 could only copy each post's URL as published into canonical_url,
+	// so posts stored before it never matched their duplicates
+	goose.NewGoMigration(14, &goose.GoFunc{RunTx: backfillCanonicalURLs}, nil),
+}
+
+// backfillCanonicalURLs sets canonical_url to urlnorm.Canonical(url) for
+// every post, the value agg stores for new posts.
+func backfillCanonicalURLs(ctx context.Context, tx *sql.Tx) error {
+	rows, err := tx.QueryContext(ctx, `SELECT id, url, canonical_url FROM posts`)
+	if err != nil {
+		return fmt.Errorf("failed to get posts: %w", err)
+	}
+
+	// The driver can't run the updates while the rows are still being read
+	canonical := map[string]string{}
+	for rows.Next() {
+		var id, url, current string
+		if err := rows.Scan(&id, &url, &current); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to read post: %w", err)
+		}
+		if c := urlnorm.Canonical(url); c != current {
+			canonical[id] = c
+		}
+	}
+	if err := rows.Close(); err != nil {
+		return fmt.Errorf("failed to get posts: %w", err)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to get posts: %w", err)
+	}
+
+	for id, c := range canonical {
+		if _, err := tx.ExecContext(ctx, `UPDATE posts SET canonical_url = $1 WHERE id = $2`, c, id); err != nil {
+			return fmt.Errorf("failed to update post %s: %w", id, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package schema
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	_ "modernc.org/sqlite"
+)
+
+func TestBackfillCanonicalURLs(t *testing.T) {
+	// The backfill only uses SQL that SQLite shares with Postgres
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "gator.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	posts := map[string]string{
+		"1": "https://Example.com/posts/1/?utm_source=rss",
+		"2": "https://example.com/posts/2#comments",
+		"3": "https://example.com/posts/3",
+	}
+	if _, err := db.ExecContext(ctx, `CREATE TABLE posts (id TEXT PRIMARY KEY, url TEXT NOT NULL, canonical_url TEXT NOT NULL)`); err != nil {
+		t.Fatal(err)
+	}
+	for id, url := range posts {
+		// What 012 left behind
+		if _, err := db.ExecContext(ctx, `INSERT INTO posts (id, url, canonical_url) VALUES ($1, $2, $2)`, id, url); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := backfillCanonicalURLs(ctx, tx); err != nil {
+		t.Fatalf("backfillCanonicalURLs: %v", err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]string{
+		"1": "https://example.com/posts/1",
+		"2": "https://example.com/posts/2",
+		"3": "https://example.com/posts/3",
+	}
+	for id, url := range want {
+		var got string
+		if err := db.QueryRowContext(ctx, `SELECT canonical_url FROM posts WHERE id = $1`, id).Scan(&got); err != nil {
+			t.Fatal(err)
+		}
+		if got != url {
+			t.Errorf("canonical_url of %s = %q, want %q", posts[id], got, url)
+		}
+	}
+}
@@ -0,0 +1,8 @@
+// Package schema embeds the goose migrations in this directory so that
+// gator can apply them itself with `gator migrate`.
+package schema
+
+import "embed"
+
+//go:embed *.sql
+var FS embed.FS
@@ -0,0 +1,19 @@
+-- name: CreateAPIToken :one
+INSERT INTO api_tokens (id, created_at, updated_at, user_id, name, token_hash)
+VALUES (?, ?, ?, ?, ?, ?)
+RETURNING *;
+
+-- name: GetAPITokensForUser :many
+SELECT * FROM api_tokens
+WHERE user_id = ?
+ORDER BY name;
+
+-- name: GetUserByAPIToken :one
+SELECT users.*
+FROM users
+JOIN api_tokens ON api_tokens.user_id = users.id
+WHERE api_tokens.token_hash = ?;
+
+-- name: DeleteAPIToken :execrows
+DELETE FROM api_tokens
+WHERE user_id = ? AND name = ?;
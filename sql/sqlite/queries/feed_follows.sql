@@ -0,0 +1,46 @@
+-- name: CreateFeedFollow :one
+INSERT INTO feed_follows (id, created_at, updated_at, user_id, feed_id)
+VALUES (?, ?, ?, ?, ?)
+RETURNING *,
+    (SELECT name FROM feeds WHERE feeds.id = feed_follows.feed_id) AS feed_name,
+    (SELECT name FROM users WHERE users.id = feed_follows.user_id) AS user_name;
+
+-- name: GetFeedByUrl :one
+SELECT * FROM feeds WHERE url = ?;
+
+-- name: GetFeedFollowsForUser :many
+SELECT feed_follows.*,
+    COALESCE(feed_follows.display_name, (SELECT name FROM feeds WHERE feeds.id = feed_follows.feed_id)) as feed_name,
+    (SELECT name FROM users WHERE users.id = feed_follows.user_id) as user_name,
+    (SELECT url FROM feeds WHERE feeds.id = feed_follows.feed_id) as feed_url
+FROM feed_follows
+WHERE feed_follows.user_id = ?;
+
+-- name: DeleteFeedFollow :exec
+DELETE FROM feed_follows
+WHERE feed_follows.user_id = sqlc.arg(user_id) AND feed_id = (SELECT id FROM feeds WHERE url = sqlc.arg(url));
+
+-- name: SetFeedFollowFolder :execrows
+UPDATE feed_follows
+SET folder = sqlc.narg(folder), updated_at = CURRENT_TIMESTAMP
+WHERE user_id = sqlc.arg(user_id) AND feed_id = (SELECT id FROM feeds WHERE url = sqlc.arg(url));
+
+-- name: ClearFolder :execrows
+UPDATE feed_follows
+SET folder = NULL, updated_at = CURRENT_TIMESTAMP
+WHERE user_id = sqlc.arg(user_id)
+    AND folder = CAST(sqlc.arg(folder) AS TEXT)
+    AND (CAST(sqlc.narg(url) AS TEXT) IS NULL OR feed_id = (SELECT id FROM feeds WHERE url = sqlc.narg(url)));
+
+-- name: RenameFolder :execrows
+UPDATE feed_follows
+SET folder = CAST(sqlc.arg(new_folder) AS TEXT), updated_at = CURRENT_TIMESTAMP
+WHERE user_id = sqlc.arg(user_id) AND folder = CAST(sqlc.arg(old_folder) AS TEXT);
+
+-- name: SetFeedFollowDisplayName :execrows
+UPDATE feed_follows
+SET display_name = sqlc.narg(display_name), updated_at = CURRENT_TIMESTAMP
+WHERE user_id = sqlc.arg(user_id) AND feed_id = (SELECT id FROM feeds WHERE url = sqlc.arg(url));
+
+-- name: CountFeedFollowers :one
+SELECT COUNT(*) FROM feed_follows WHERE feed_id = ?;
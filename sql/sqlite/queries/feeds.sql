@@ -0,0 +1,35 @@
+-- name: CreateFeed :one
+INSERT INTO feeds (id, created_at, updated_at, name, url, user_id)
+VALUES (?, ?, ?, ?, ?, ?)
+RETURNING *;
+
+-- name: GetFeedsWithUserNames :many
+SELECT
+    feeds.id,
+    feeds.created_at,
+    feeds.updated_at,
+    feeds.name,
+    feeds.url,
+    users.name AS user_name
+FROM
+    feeds
+    JOIN users ON feeds.user_id = users.id;
+
+-- name: GetFeedFetchTimes :many
+SELECT last_fetched_at FROM feeds;
+
+-- name: MarkFeedFetched :exec
+UPDATE feeds
+SET last_fetched_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+WHERE id = ?;
+
+-- name: GetNextFeedToFetch :one
+SELECT *
+FROM feeds
+ORDER BY last_fetched_at ASC NULLS FIRST
+LIMIT 1;
+
+-- name: SetFeedRetention :exec
+UPDATE feeds
+SET retention_days = sqlc.narg(retention_days), retention_max_posts = sqlc.narg(retention_max_posts), updated_at = CURRENT_TIMESTAMP
+WHERE id = sqlc.arg(id);
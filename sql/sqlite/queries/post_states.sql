@@ -0,0 +1,11 @@
+-- name: SetPostRead :exec
+INSERT INTO post_states (id, created_at, updated_at, user_id, post_id, read_at)
+VALUES (?, ?, ?, ?, ?, ?)
+ON CONFLICT (user_id, post_id) DO UPDATE
+SET read_at = excluded.read_at, updated_at = excluded.updated_at;
+
+-- name: SetPostSaved :exec
+INSERT INTO post_states (id, created_at, updated_at, user_id, post_id, saved_at)
+VALUES (?, ?, ?, ?, ?, ?)
+ON CONFLICT (user_id, post_id) DO UPDATE
+SET saved_at = excluded.saved_at, updated_at = excluded.updated_at;
@@ -0,0 +1,109 @@
+-- Times are stored as text with a UTC offset, which doesn't order correctly
+-- as a plain string, so they are compared through datetime().
+
+-- name: CreatePost :one
+INSERT INTO posts (id, created_at, updated_at, title, url, description, published_at, feed_id, author, canonical_url)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+RETURNING *;
+
+-- name: GetPostsForUser :many
+SELECT posts.*,
+    COALESCE(feed_follows.display_name, feeds.name) AS feed_name,
+    post_states.read_at,
+    post_states.saved_at
+FROM posts
+JOIN feeds ON posts.feed_id = feeds.id
+JOIN feed_follows ON feeds.id = feed_follows.feed_id
+LEFT JOIN post_states ON post_states.post_id = posts.id AND post_states.user_id = feed_follows.user_id
+WHERE feed_follows.user_id = sqlc.arg(user_id)
+    AND (CAST(sqlc.narg(folder) AS TEXT) IS NULL OR feed_follows.folder = sqlc.narg(folder))
+    AND (sqlc.narg(feed_id) IS NULL OR posts.feed_id = sqlc.narg(feed_id))
+    AND (NOT CAST(sqlc.arg(unread_only) AS BOOLEAN) OR post_states.read_at IS NULL)
+    AND (NOT CAST(sqlc.arg(saved_only) AS BOOLEAN) OR post_states.saved_at IS NOT NULL)
+    AND (sqlc.narg(published_after) IS NULL OR datetime(posts.published_at) > datetime(sqlc.narg(published_after)))
+ORDER BY CASE WHEN CAST(sqlc.arg(oldest_first) AS BOOLEAN) THEN datetime(posts.published_at) END ASC, datetime(posts.published_at) DESC
+LIMIT sqlc.arg('limit') OFFSET sqlc.arg('offset');
+
+-- name: GetPostForUser :one
+SELECT posts.*,
+    COALESCE(feed_follows.display_name, feeds.name) AS feed_name,
+    post_states.read_at,
+    post_states.saved_at
+FROM posts
+JOIN feeds ON posts.feed_id = feeds.id
+JOIN feed_follows ON feeds.id = feed_follows.feed_id
+LEFT JOIN post_states ON post_states.post_id = posts.id AND post_states.user_id = feed_follows.user_id
+WHERE feed_follows.user_id = sqlc.arg(user_id) AND posts.id = sqlc.arg(id);
+
+-- name: GetPostIDInRange :one
+SELECT id FROM posts
+WHERE id BETWEEN sqlc.arg(id_from) AND sqlc.arg(id_to)
+ORDER BY id
+LIMIT 1;
+
+-- name: GetUnreadCountsForUser :many
+SELECT posts.feed_id, COUNT(*) AS unread
+FROM posts
+JOIN feed_follows ON posts.feed_id = feed_follows.feed_id
+LEFT JOIN post_states ON post_states.post_id = posts.id AND post_states.user_id = feed_follows.user_id
+WHERE feed_follows.user_id = ? AND post_states.read_at IS NULL
+GROUP BY posts.feed_id;
+
+-- name: GetPrunablePosts :many
+WITH ranked AS (
+    SELECT
+        posts.id,
+        posts.title,
+        posts.published_at,
+        posts.feed_id,
+        feeds.name AS feed_name,
+        ROW_NUMBER() OVER (PARTITION BY posts.feed_id ORDER BY datetime(posts.published_at) DESC) AS position,
+        COALESCE(feeds.retention_days, CAST(sqlc.arg(default_days) AS INTEGER)) AS keep_days,
+        COALESCE(feeds.retention_max_posts, CAST(sqlc.arg(default_max_posts) AS INTEGER)) AS keep_posts
+    FROM posts
+    JOIN feeds ON posts.feed_id = feeds.id
+)
+SELECT ranked.id, ranked.title, ranked.feed_name, ranked.published_at
+FROM ranked
+WHERE NOT EXISTS (
+        SELECT 1 FROM post_states
+        WHERE post_states.post_id = ranked.id AND post_states.saved_at IS NOT NULL
+    )
+    AND (
+        (ranked.keep_days > 0 AND datetime(ranked.published_at) < datetime(sqlc.arg(now), '-' || ranked.keep_days || ' days'))
+        OR (
+            ranked.keep_posts > 0 AND ranked.position > ranked.keep_posts
+            AND (
+                ranked.keep_days = 0
+                OR NOT EXISTS (
+                    SELECT 1 FROM feed_follows
+                    WHERE feed_follows.feed_id = ranked.feed_id
+                        AND NOT EXISTS (
+                            SELECT 1 FROM post_states
+                            WHERE post_states.post_id = ranked.id
+                                AND post_states.user_id = feed_follows.user_id
+                                AND post_states.read_at IS NOT NULL
+                        )
+                )
+            )
+        )
+    )
+ORDER BY ranked.feed_name, datetime(ranked.published_at);
+
+-- name: DeletePosts :execrows
+DELETE FROM posts
+WHERE id IN (sqlc.slice(ids))
+    AND NOT EXISTS (
+        SELECT 1 FROM post_states
+        WHERE post_states.post_id = posts.id AND post_states.saved_at IS NOT NULL
+    );
+
+-- name: GetFeedNamesForCanonicalURLs :many
+SELECT DISTINCT posts.canonical_url, COALESCE(feed_follows.display_name, feeds.name) AS feed_name
+FROM posts
+JOIN feeds ON posts.feed_id = feeds.id
+JOIN feed_follows ON feeds.id = feed_follows.feed_id
+WHERE feed_follows.user_id = sqlc.arg(user_id)
+    AND (CAST(sqlc.narg(folder) AS TEXT) IS NULL OR feed_follows.folder = sqlc.narg(folder))
+    AND posts.canonical_url IN (sqlc.slice(canonical_urls))
+ORDER BY posts.canonical_url, feed_name;
@@ -0,0 +1,23 @@
+-- name: CreateRule :one
+INSERT INTO rules (id, created_at, updated_at, user_id, action, scope, pattern, is_regex, apply_at_ingest)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+RETURNING *;
+
+-- name: GetRulesForUser :many
+SELECT *
+FROM rules
+WHERE user_id = ?
+ORDER BY created_at ASC;
+
+-- name: DeleteRule :execrows
+DELETE FROM rules
+WHERE id = sqlc.arg(id) AND user_id = sqlc.arg(user_id);
+
+-- name: GetIngestMuteRulesForFeed :many
+SELECT rules.*, COALESCE(feed_follows.display_name, feeds.name) AS feed_name
+FROM rules
+JOIN feed_follows ON rules.user_id = feed_follows.user_id
+JOIN feeds ON feeds.id = feed_follows.feed_id
+WHERE feed_follows.feed_id = ?
+    AND rules.action = 'mute'
+    AND rules.apply_at_ingest;
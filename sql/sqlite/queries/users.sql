@@ -0,0 +1,16 @@
+-- name: CreateUser :one
+INSERT INTO users (id, created_at, updated_at, name)
+VALUES (?, ?, ?, ?)
+RETURNING *;
+
+-- name: GetUser :one
+SELECT *
+FROM users
+WHERE name = ?;
+
+-- name: DeleteAllUsers :exec
+DELETE FROM users;
+
+-- name: GetUsers :many
+SELECT name
+FROM users;
@@ -0,0 +1,76 @@
+-- +goose Up
+CREATE TABLE users (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    name TEXT UNIQUE NOT NULL
+);
+
+CREATE TABLE feeds (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    name TEXT NOT NULL,
+    url TEXT UNIQUE NOT NULL,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    last_fetched_at TIMESTAMP NULL,
+    retention_days INTEGER NULL,
+    retention_max_posts INTEGER NULL
+);
+
+CREATE TABLE feed_follows (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    feed_id UUID NOT NULL REFERENCES feeds(id) ON DELETE CASCADE,
+    folder TEXT NULL,
+    display_name TEXT NULL,
+    UNIQUE(user_id, feed_id)
+);
+
+CREATE TABLE posts (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    title TEXT NOT NULL,
+    url TEXT NOT NULL,
+    description TEXT,
+    published_at TIMESTAMP NOT NULL,
+    feed_id UUID NOT NULL REFERENCES feeds(id) ON DELETE CASCADE,
+    author TEXT NULL,
+    canonical_url TEXT NOT NULL,
+    UNIQUE(feed_id, url)
+);
+CREATE INDEX posts_canonical_url_idx ON posts (canonical_url);
+
+CREATE TABLE rules (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    action TEXT NOT NULL CHECK (action IN ('mute', 'highlight')),
+    scope TEXT NOT NULL CHECK (scope IN ('title', 'description', 'author', 'feed')),
+    pattern TEXT NOT NULL,
+    is_regex BOOLEAN NOT NULL DEFAULT FALSE,
+    apply_at_ingest BOOLEAN NOT NULL DEFAULT FALSE
+);
+
+CREATE TABLE post_states (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    post_id UUID NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+    read_at TIMESTAMP NULL,
+    saved_at TIMESTAMP NULL,
+    UNIQUE(user_id, post_id)
+);
+
+-- +goose Down
+DROP TABLE post_states;
+DROP TABLE rules;
+DROP TABLE posts;
+DROP TABLE feed_follows;
+DROP TABLE feeds;
+DROP TABLE users;
@@ -0,0 +1,9 @@
+// Package schema embeds the goose migrations for the SQLite backend. The
+// SQLite schema starts from the current Postgres schema rather than
+// replaying its history, so its version numbers are independent.
+package schema
+
+import "embed"
+
+//go:embed *.sql
+var FS embed.FS
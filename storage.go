@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/josequiceno2000/gator/internal/database"
+	"github.com/josequiceno2000/gator/internal/sqlitestore"
+	"github.com/josequiceno2000/gator/sql/schema"
+	sqliteschema "github.com/josequiceno2000/gator/sql/sqlite/schema"
+	"github.com/pressly/goose/v3"
+	_ "modernc.org/sqlite"
+)
+
+// backend describes the database a db_url points at: which driver opens
+// it, which migrations describe its schema and how to build queries on it.
+type backend struct {
+	driver       string
+	dsn          string
+	dialect      goose.Dialect
+	schema       fs.FS
+	goMigrations []*goose.Migration
+	queries      func(database.DBTX) database.Querier
+}
+
+// parseDBURL picks a backend from the scheme of dbURL. sqlite: URLs name a
+// local database file, for example sqlite:///home/me/gator.db or
+// sqlite:~/gator.db. postgres:// and postgresql:// URLs, and key=value
+// connection strings, go to the Postgres driver.
+func parseDBURL(dbURL string) (backend, error) {
+	scheme, _, _ := strings.Cut(dbURL, ":")
+	switch strings.ToLower(scheme) {
+	case "postgres", "postgresql":
+		if _, err := url.Parse(dbURL); err != nil {
+			return backend{}, err
+		}
+		return postgresBackend(dbURL), nil
+	case "sqlite", "sqlite3":
+		path, err := sqlitePath(dbURL)
+		if err != nil {
+			return backend{}, err
+		}
+
+		// Times are written in a format SQLite's date functions understand,
+		// foreign keys are off by default in SQLite, and WAL with a busy
+		// timeout lets agg write while other commands read
+		params := url.Values{}
+		params.Add("_pragma", "foreign_keys(1)")
+		params.Add("_pragma", "journal_mode(WAL)")
+		params.Add("_pragma", "busy_timeout(5000)")
+		params.Set("_time_format", "sqlite")
+
+		// SQLite decodes the path as a URI, so ?, # and % in it are escaped
+		dsn := url.URL{Scheme: "file", Path: path, OmitHost: true, RawQuery: params.Encode()}
+
+		return backend{
+			driver:  "sqlite",
+			dsn:     dsn.String(),
+			dialect: goose.DialectSQLite3,
+			schema:  sqliteschema.FS,
+			queries: func(db database.DBTX) database.Querier { return sqlitestore.New(db) },
+		}, nil
+	default:
+		if strings.Contains(dbURL, "://") {
+			return backend{}, fmt.Errorf("unsupported db_url scheme %q", scheme)
+		}
+		return postgresBackend(dbURL), nil
+	}
+}
+
+func postgresBackend(dbURL string) backend {
+	return backend{
+		driver:       "postgres",
+		dsn:          dbURL,
+		dialect:      goose.DialectPostgres,
+		schema:       schema.FS,
+		goMigrations: schema.GoMigrations,
+		queries:      func(db database.DBTX) database.Querier { return database.New(db) },
+	}
+}
+
+func sqlitePath(dbURL string) (string, error) {
+	_, path, _ := strings.Cut(dbURL, ":")
+	path = strings.TrimPrefix(path, "//")
+	if path == "" {
+		return "", fmt.Errorf("db_url %q doesn't name a database file", dbURL)
+	}
+
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("failed to find home directory: %w", err)
+		}
+		path = filepath.Join(home, strings.TrimPrefix(path, "~"))
+	}
+	return path, nil
+}
+
+// openDatabase opens the database named by dbURL and returns it together
+// with the backend it was opened with.
+func openDatabase(dbURL string) (*sql.DB, backend, error) {
+	b, err := parseDBURL(dbURL)
+	if err != nil {
+		return nil, backend{}, err
+	}
+
+	db, err := sql.Open(b.driver, b.dsn)
+	if err != nil {
+		return nil, backend{}, err
+	}
+	return db, b, nil
+}
+
+// connectDatabase opens the database named by dbURL and checks that it
+// answers within timeout.
+func connectDatabase(dbURL string, timeout time.Duration) (*sql.DB, backend, error) {
+	db, b, err := openDatabase(dbURL)
+	if err != nil {
+		return nil, backend{}, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, backend{}, err
+	}
+	return db, b, nil
+}
+
+// isUniqueViolation reports whether err is a unique constraint failure from
+// either backend.
+func isUniqueViolation(err error) bool {
+	msg := err.Error()
+	return strings.Contains(msg, "duplicate key value violates unique constraint") ||
+		strings.Contains(msg, "UNIQUE constraint failed")
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/josequiceno2000/gator/internal/database"
+)
+
+// API tokens authenticate `gator serve` requests as a user. Only a hash of
+// each token is stored, so a token is shown once, when it is created.
+
+const apiTokenPrefix = "gator_"
+
+func handlerToken(s *state, cmd command, user database.User) error {
+	args := cmd.Arguments[1:]
+
+	switch cmd.Arguments[0] {
+	case "create":
+		if len(args) < 1 {
+			return errors.New("token create: token name is required")
+		}
+
+		token, err := newAPIToken()
+		if err != nil {
+			return fmt.Errorf("token create: %w", err)
+		}
+
+		_, err = s.DB.CreateAPIToken(context.Background(), database.CreateAPITokenParams{
+			ID:        uuid.New(),
+			CreatedAt: time.Now().UTC(),
+			UpdatedAt: time.Now().UTC(),
+			UserID:    user.ID,
+			Name:      args[0],
+			TokenHash: hashAPIToken(token),
+		})
+		if err != nil {
+			if isUniqueViolation(err) {
+				return fmt.Errorf("token create: you already have a token named %s", args[0])
+			}
+			return fmt.Errorf("token create: failed to create token: %w", err)
+		}
+
+		fmt.Println(token)
+		fmt.Println("Keep this token somewhere safe; it can't be shown again")
+	case "list":
+		tokens, err := s.DB.GetAPITokensForUser(context.Background(), user.ID)
+		if err != nil {
+			return fmt.Errorf("token list: failed to get tokens: %w", err)
+		}
+		if len(tokens) == 0 {
+			fmt.Println("No tokens yet; create one with `gator token create <name>`")
+			return nil
+		}
+
+		for _, token := range tokens {
+			fmt.Printf("%-20s created %s\n", token.Name, token.CreatedAt.Format("2006-01-02 15:04"))
+		}
+	case "rm":
+		if len(args) < 1 {
+			return errors.New("token rm: token name is required")
+		}
+
+		n, err := s.DB.DeleteAPIToken(context.Background(), database.DeleteAPITokenParams{
+			UserID: user.ID,
+			Name:   args[0],
+		})
+		if err != nil {
+			return fmt.Errorf("token rm: failed to delete token: %w", err)
+		}
+		if n == 0 {
+			return fmt.Errorf("token rm: no token named %s", args[0])
+		}
+
+		fmt.Printf("Revoked token: %s\n", args[0])
+	default:
+		return fmt.Errorf("token: unknown subcommand: %s", cmd.Arguments[0])
+	}
+
+	return nil
+}
+
+func newAPIToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	return apiTokenPrefix + hex.EncodeToString(b), nil
+}
+
+// hashAPIToken returns what is stored for token. Tokens are random and
+// long, so a fast unsalted hash is enough to keep a leaked database from
+// leaking usable tokens.
+func hashAPIToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// userForAPIToken returns the user token belongs to, or sql.ErrNoRows.
+func userForAPIToken(ctx context.Context, s *state, token string) (database.User, error) {
+	return s.DB.GetUserByAPIToken(ctx, hashAPIToken(token))
+}
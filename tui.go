@@ -0,0 +1,496 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/url"
+	"os/exec"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/google/uuid"
+	"github.com/josequiceno2000/gator/internal/database"
+	"github.com/josequiceno2000/gator/internal/render"
+)
+
+const tuiPostLimit = 200
+
+const (
+	paneFeeds = iota
+	panePosts
+	paneBody
+	paneCount
+)
+
+var (
+	tuiPaneStyle    = lipgloss.NewStyle().Border(lipgloss.RoundedBorder()).BorderForeground(lipgloss.Color("8"))
+	tuiFocusedStyle = tuiPaneStyle.BorderForeground(lipgloss.Color("12"))
+	tuiCursorStyle  = lipgloss.NewStyle().Reverse(true)
+	tuiStatusStyle  = lipgloss.NewStyle().Foreground(lipgloss.Color("8"))
+	tuiTitleStyle   = lipgloss.NewStyle().Bold(true)
+)
+
+// tuiFilter is an entry in the feed pane: all posts, a folder or a single feed.
+type tuiFilter struct {
+	label  string
+	folder sql.NullString
+	feedID uuid.NullUUID
+}
+
+type tuiModel struct {
+	s            *state
+	user         database.User
+	rules        []compiledRule
+	refreshEvery time.Duration
+
+	filters    []tuiFilter
+	filterIdx  int
+	active     tuiFilter
+	posts      []database.GetPostsForUserRow
+	postIdx    int
+	bodyOffset int
+
+	focus  int
+	width  int
+	height int
+	status string
+}
+
+type filtersLoadedMsg []tuiFilter
+
+type postsLoadedMsg struct {
+	posts  []database.GetPostsForUserRow
+	keepID uuid.UUID
+}
+
+type tuiRefreshMsg time.Time
+
+type tuiStatusMsg string
+
+type tuiErrMsg struct{ err error }
+
+func handlerTui(s *state, cmd command, user database.User) error {
+	refreshEvery := cmd.durationFlag("refresh")
+	if refreshEvery <= 0 {
+		return fmt.Errorf("tui: refresh interval must be positive")
+	}
+
+	rules, err := s.DB.GetRulesForUser(context.Background(), user.ID)
+	if err != nil {
+		return fmt.Errorf("tui: failed to get rules: %w", err)
+	}
+
+	compiledRules, err := compileRules(rules)
+	if err != nil {
+		return fmt.Errorf("tui: %w", err)
+	}
+
+	m := tuiModel{
+		s:            s,
+		user:         user,
+		rules:        compiledRules,
+		refreshEvery: refreshEvery,
+		active:       tuiFilter{label: "All feeds"},
+		focus:        panePosts,
+	}
+
+	if _, err := tea.NewProgram(m, tea.WithAltScreen()).Run(); err != nil {
+		return fmt.Errorf("tui: %w", err)
+	}
+	return nil
+}
+
+func (m tuiModel) Init() tea.Cmd {
+	return tea.Batch(m.loadFilters(), m.loadPosts(uuid.Nil), m.tick())
+}
+
+func (m tuiModel) tick() tea.Cmd {
+	return tea.Tick(m.refreshEvery, func(t time.Time) tea.Msg {
+		return tuiRefreshMsg(t)
+	})
+}
+
+func (m tuiModel) loadFilters() tea.Cmd {
+	return func() tea.Msg {
+		feedFollows, err := m.s.DB.GetFeedFollowsForUser(context.Background(), m.user.ID)
+		if err != nil {
+			return tuiErrMsg{fmt.Errorf("failed to get feed follows: %w", err)}
+		}
+
+		groups := make(map[string][]database.GetFeedFollowsForUserRow)
+		var unfiled []database.GetFeedFollowsForUserRow
+		for _, ff := range feedFollows {
+			if ff.Folder.Valid {
+				groups[ff.Folder.String] = append(groups[ff.Folder.String], ff)
+			} else {
+				unfiled = append(unfiled, ff)
+			}
+		}
+
+		folders := make([]string, 0, len(groups))
+		for folder := range groups {
+			folders = append(folders, folder)
+		}
+		sort.Strings(folders)
+
+		filters := []tuiFilter{{label: "All feeds"}}
+		for _, folder := range folders {
+			filters = append(filters, tuiFilter{
+				label:  folder + "/",
+				folder: sql.NullString{String: folder, Valid: true},
+			})
+			for _, ff := range groups[folder] {
+				filters = append(filters, tuiFilter{
+					label:  "  " + ff.FeedName,
+					feedID: uuid.NullUUID{UUID: ff.FeedID, Valid: true},
+				})
+			}
+		}
+		for _, ff := range unfiled {
+			filters = append(filters, tuiFilter{
+				label:  ff.FeedName,
+				feedID: uuid.NullUUID{UUID: ff.FeedID, Valid: true},
+			})
+		}
+
+		return filtersLoadedMsg(filters)
+	}
+}
+
+// loadPosts reloads the post list for the active filter, keeping keepID
+// selected if it is still present.
+func (m tuiModel) loadPosts(keepID uuid.UUID) tea.Cmd {
+	filter := m.active
+	return func() tea.Msg {
+		posts, err := m.s.DB.GetPostsForUser(context.Background(), database.GetPostsForUserParams{
+			UserID: m.user.ID,
+			Folder: filter.folder,
+			FeedID: filter.feedID,
+			Limit:  tuiPostLimit,
+			Offset: 0,
+		})
+		if err != nil {
+			return tuiErrMsg{fmt.Errorf("failed to get posts: %w", err)}
+		}
+
+		visible := posts[:0]
+		for _, post := range posts {
+			if muted, _ := classifyPost(m.rules, tuiPostFields(post)); muted {
+				continue
+			}
+			visible = append(visible, post)
+		}
+
+		return postsLoadedMsg{posts: visible, keepID: keepID}
+	}
+}
+
+func tuiPostFields(post database.GetPostsForUserRow) postFields {
+	return postFields{
+		Title:       post.Title,
+		Description: post.Description.String,
+		Author:      post.Author.String,
+		FeedName:    post.FeedName,
+	}
+}
+
+func (m tuiModel) currentPost() (database.GetPostsForUserRow, bool) {
+	if m.postIdx < 0 || m.postIdx >= len(m.posts) {
+		return database.GetPostsForUserRow{}, false
+	}
+	return m.posts[m.postIdx], true
+}
+
+func (m tuiModel) setRead(read bool) (tuiModel, tea.Cmd) {
+	post, ok := m.currentPost()
+	if !ok || post.ReadAt.Valid == read {
+		return m, nil
+	}
+
+	now := time.Now().UTC()
+	readAt := sql.NullTime{Time: now, Valid: read}
+	m.posts[m.postIdx].ReadAt = readAt
+
+	return m, func() tea.Msg {
+		err := m.s.DB.SetPostRead(context.Background(), database.SetPostReadParams{
+			ID:        uuid.New(),
+			CreatedAt: now,
+			UpdatedAt: now,
+			UserID:    m.user.ID,
+			PostID:    post.ID,
+			ReadAt:    readAt,
+		})
+		if err != nil {
+			return tuiErrMsg{fmt.Errorf("failed to update read state: %w", err)}
+		}
+		return nil
+	}
+}
+
+func (m tuiModel) toggleSaved() (tuiModel, tea.Cmd) {
+	post, ok := m.currentPost()
+	if !ok {
+		return m, nil
+	}
+
+	now := time.Now().UTC()
+	savedAt := sql.NullTime{Time: now, Valid: !post.SavedAt.Valid}
+	m.posts[m.postIdx].SavedAt = savedAt
+
+	return m, func() tea.Msg {
+		err := m.s.DB.SetPostSaved(context.Background(), database.SetPostSavedParams{
+			ID:        uuid.New(),
+			CreatedAt: now,
+			UpdatedAt: now,
+			UserID:    m.user.ID,
+			PostID:    post.ID,
+			SavedAt:   savedAt,
+		})
+		if err != nil {
+			return tuiErrMsg{fmt.Errorf("failed to update saved state: %w", err)}
+		}
+		if savedAt.Valid {
+			return tuiStatusMsg("Saved: " + post.Title)
+		}
+		return tuiStatusMsg("Unsaved: " + post.Title)
+	}
+}
+
+func (m tuiModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width, m.height = msg.Width, msg.Height
+	case filtersLoadedMsg:
+		m.filters = msg
+		m.filterIdx = clamp(m.filterIdx, 0, len(m.filters)-1)
+	case postsLoadedMsg:
+		m.posts = msg.posts
+		m.postIdx = clamp(m.postIdx, 0, len(m.posts)-1)
+		for i, post := range m.posts {
+			if post.ID == msg.keepID {
+				m.postIdx = i
+				break
+			}
+		}
+	case tuiRefreshMsg:
+		post, _ := m.currentPost()
+		return m, tea.Batch(m.loadFilters(), m.loadPosts(post.ID), m.tick())
+	case tuiStatusMsg:
+		m.status = string(msg)
+	case tuiErrMsg:
+		m.status = "Error: " + msg.err.Error()
+	case tea.KeyMsg:
+		return m.handleKey(msg)
+	}
+	return m, nil
+}
+
+func (m tuiModel) handleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "q", "ctrl+c":
+		return m, tea.Quit
+	case "tab":
+		m.focus = (m.focus + 1) % paneCount
+	case "shift+tab":
+		m.focus = (m.focus + paneCount - 1) % paneCount
+	case "j", "down":
+		m = m.move(1)
+	case "k", "up":
+		m = m.move(-1)
+	case "pgdown", " ":
+		m = m.move(m.paneHeight())
+	case "pgup":
+		m = m.move(-m.paneHeight())
+	case "enter":
+		switch m.focus {
+		case paneFeeds:
+			if m.filterIdx < len(m.filters) {
+				m.active = m.filters[m.filterIdx]
+				m.postIdx, m.bodyOffset = 0, 0
+				m.focus = panePosts
+				return m, m.loadPosts(uuid.Nil)
+			}
+		case panePosts:
+			m.focus = paneBody
+			return m.setRead(true)
+		}
+	case "r":
+		post, _ := m.currentPost()
+		return m.setRead(!post.ReadAt.Valid)
+	case "s":
+		return m.toggleSaved()
+	case "o":
+		post, ok := m.currentPost()
+		if !ok {
+			break
+		}
+		if err := openURL(post.Url); err != nil {
+			m.status = "Error: " + err.Error()
+			break
+		}
+		m.status = "Opened: " + post.Url
+		return m.setRead(true)
+	case "R":
+		post, _ := m.currentPost()
+		return m, tea.Batch(m.loadFilters(), m.loadPosts(post.ID))
+	}
+	return m, nil
+}
+
+func (m tuiModel) move(delta int) tuiModel {
+	switch m.focus {
+	case paneFeeds:
+		m.filterIdx = clamp(m.filterIdx+delta, 0, len(m.filters)-1)
+	case panePosts:
+		next := clamp(m.postIdx+delta, 0, len(m.posts)-1)
+		if next != m.postIdx {
+			m.postIdx, m.bodyOffset = next, 0
+		}
+	case paneBody:
+		m.bodyOffset = max(m.bodyOffset+delta, 0)
+	}
+	return m
+}
+
+func (m tuiModel) paneHeight() int {
+	// Two rows for the pane borders and one for the status line
+	return max(m.height-3, 1)
+}
+
+func (m tuiModel) View() string {
+	if m.width == 0 {
+		return "Loading..."
+	}
+
+	height := m.paneHeight()
+	feedsWidth := max(m.width/5, 10)
+	postsWidth := max(m.width*2/5, 20)
+	bodyWidth := max(m.width-feedsWidth-postsWidth-6, 10)
+
+	feedLines := make([]string, len(m.filters))
+	for i, filter := range m.filters {
+		feedLines[i] = filter.label
+	}
+
+	postLines := make([]string, len(m.posts))
+	for i, post := range m.posts {
+		mark := " "
+		if !post.ReadAt.Valid {
+			mark = "●"
+		}
+		if post.SavedAt.Valid {
+			mark = "★"
+		}
+		if _, highlighted := classifyPost(m.rules, tuiPostFields(post)); highlighted {
+			mark += "!"
+		} else {
+			mark += " "
+		}
+		postLines[i] = mark + " " + post.Title
+	}
+
+	panes := lipgloss.JoinHorizontal(lipgloss.Top,
+		m.renderPane(paneFeeds, renderList(feedLines, m.filterIdx, feedsWidth, height), feedsWidth, height),
+		m.renderPane(panePosts, renderList(postLines, m.postIdx, postsWidth, height), postsWidth, height),
+		m.renderPane(paneBody, m.renderBody(bodyWidth, height), bodyWidth, height),
+	)
+
+	status := fmt.Sprintf("[%s] tab: pane  j/k: move  enter: open  r: read  s: save  o: browser  R: refresh  q: quit", m.active.label)
+	if m.status != "" {
+		status = m.status + "  |  " + status
+	}
+
+	return panes + "\n" + tuiStatusStyle.Render(ansi.Truncate(status, m.width, "…"))
+}
+
+func (m tuiModel) renderPane(pane int, content string, width, height int) string {
+	style := tuiPaneStyle
+	if m.focus == pane {
+		style = tuiFocusedStyle
+	}
+	return style.Width(width).Height(height).MaxHeight(height + 2).Render(content)
+}
+
+func (m tuiModel) renderBody(width, height int) string {
+	post, ok := m.currentPost()
+	if !ok {
+		return "No posts"
+	}
+
+	var b strings.Builder
+	b.WriteString(tuiTitleStyle.Render(ansi.Wordwrap(post.Title, width, "")) + "\n")
+	fmt.Fprintf(&b, "%s · %s\n", post.FeedName, post.PublishedAt.Format("2006-01-02 15:04"))
+	if post.Author.Valid {
+		fmt.Fprintf(&b, "by %s\n", post.Author.String)
+	}
+	b.WriteString(post.Url + "\n\n")
+	b.WriteString(render.Text(post.Description.String, width))
+
+	lines := strings.Split(ansi.Wrap(b.String(), width, ""), "\n")
+	offset := min(m.bodyOffset, max(len(lines)-height, 0))
+	lines = lines[offset:]
+	if len(lines) > height {
+		lines = lines[:height]
+	}
+	return strings.Join(lines, "\n")
+}
+
+// renderList renders the window of lines that keeps the cursor visible.
+func renderList(lines []string, cursor, width, height int) string {
+	start := 0
+	if cursor >= height {
+		start = cursor - height + 1
+	}
+	end := min(start+height, len(lines))
+
+	out := make([]string, 0, end-start)
+	for i := start; i < end; i++ {
+		line := ansi.Truncate(lines[i], width, "…")
+		if i == cursor {
+			line = tuiCursorStyle.Render(line + strings.Repeat(" ", max(width-ansi.StringWidth(line), 0)))
+		}
+		out = append(out, line)
+	}
+	return strings.Join(out, "\n")
+}
+
+// openURL opens a post's link in the browser. Links come from feeds, so
+// anything but a web URL is refused rather than handed to the OS opener,
+// which would also run files and other schemes.
+func openURL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("not opening %q: only http and https links can be opened", rawURL)
+	}
+
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", rawURL)
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", rawURL)
+	default:
+		cmd = exec.Command("xdg-open", rawURL)
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to open browser: %w", err)
+	}
+	go cmd.Wait()
+	return nil
+}
+
+func clamp(v, lo, hi int) int {
+	if v > hi {
+		v = hi
+	}
+	if v < lo {
+		v = lo
+	}
+	return v
+}
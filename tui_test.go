@@ -0,0 +1,22 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestOpenURLRefusesOtherSchemes(t *testing.T) {
+	for _, raw := range []string{
+		"file:///etc/passwd",
+		"javascript:alert(1)",
+		"smb://example.com/share/run.exe",
+		"/posts/1",
+		"https:///no-host",
+		"",
+	} {
+		err := openURL(raw)
+		if err == nil || !strings.Contains(err.Error(), "only http and https") {
+			t.Errorf("openURL(%q) = %v, want it refused", raw, err)
+		}
+	}
+}